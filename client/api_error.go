@@ -0,0 +1,104 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// APIError is the error payload returned by the NSX API for a failed request.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response. It is not part of
+	// the NSX payload.
+	StatusCode    int        `json:"-"`
+	HTTPStatus    string     `json:"httpStatus,omitempty"`
+	ErrorCode     int        `json:"error_code,omitempty"`
+	ErrorMessage  string     `json:"error_message,omitempty"`
+	ModuleName    string     `json:"module_name,omitempty"`
+	RelatedErrors []APIError `json:"related_errors,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "NSX API returned status code %d", e.StatusCode)
+	if e.HTTPStatus != "" {
+		fmt.Fprintf(&b, " (%s)", e.HTTPStatus)
+	}
+	if e.ErrorCode != 0 {
+		fmt.Fprintf(&b, ", error code %d", e.ErrorCode)
+	}
+	if e.ModuleName != "" {
+		fmt.Fprintf(&b, " from %s", e.ModuleName)
+	}
+	if e.ErrorMessage != "" {
+		fmt.Fprintf(&b, ": %s", e.ErrorMessage)
+	}
+	return b.String()
+}
+
+// IsNotFound reports whether err is an APIError for a 404 Not Found response.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsPreconditionFailed reports whether err is an APIError for a 412
+// Precondition Failed response, which NSX returns when the _revision sent with
+// an update no longer matches the object.
+func IsPreconditionFailed(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// parseAPIError builds an APIError from a non-2xx response. The body is read
+// and closed. Bodies which are not an NSX error payload are kept as the error
+// message.
+func parseAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if resp.Body == nil {
+		return apiErr
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	if err := json.Unmarshal(body, apiErr); err != nil {
+		apiErr.ErrorMessage = strings.TrimSpace(string(body))
+	}
+	apiErr.StatusCode = resp.StatusCode
+	return apiErr
+}
+
+// checkResponse returns an APIError for responses outside the 2xx range.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	return parseAPIError(resp)
+}
+
+// decodeResponse decodes the JSON body of a successful response into v and
+// closes the body.
+func decodeResponse(resp *http.Response, v any) error {
+	defer func() { _ = resp.Body.Close() }()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode NSX response: %w", err)
+	}
+	return nil
+}
+
+// closeResponse discards and closes the body of a response which carries no
+// data the caller needs.
+func closeResponse(resp *http.Response) error {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
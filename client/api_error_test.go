@@ -0,0 +1,99 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"terraform-provider-nsx-intervlan-routing/helpers"
+)
+
+func TestClientReturnsAPIError(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprint(w, `{
+			"httpStatus": "BAD_REQUEST",
+			"error_code": 500012,
+			"module_name": "Policy",
+			"error_message": "Invalid attachment.",
+			"related_errors": [
+				{"error_code": 8311, "module_name": "switching service", "error_message": "Context ID not found."}
+			]
+		}`)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	_, err = c.GetSegmentPort(context.Background(), "segment-1", "port-1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.HTTPStatus != "BAD_REQUEST" {
+		t.Errorf("unexpected status: %d %s", apiErr.StatusCode, apiErr.HTTPStatus)
+	}
+	if apiErr.ErrorCode != 500012 || apiErr.ModuleName != "Policy" || apiErr.ErrorMessage != "Invalid attachment." {
+		t.Errorf("unexpected error payload: %+v", apiErr)
+	}
+	if len(apiErr.RelatedErrors) != 1 || apiErr.RelatedErrors[0].ErrorCode != 8311 {
+		t.Errorf("unexpected related errors: %+v", apiErr.RelatedErrors)
+	}
+	if IsNotFound(err) {
+		t.Error("expected IsNotFound to be false for a 400 response")
+	}
+}
+
+func TestClientReturnsNotFound(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	_, err = c.GetSegmentPort(context.Background(), "segment-1", "port-1")
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestClientSendsRevisionAndReportsConflict(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request body: %s", err)
+		}
+		if body["_revision"] != float64(0) {
+			t.Errorf("expected _revision 0 to be sent, got %v", body["_revision"])
+		}
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_, _ = fmt.Fprint(w, `{"error_code": 604, "error_message": "The object was modified by somebody else."}`)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	revision := int64(0)
+	err = c.PatchSegmentPort(context.Background(), helpers.PatchSegmentPortRequest{
+		SegmentId:      "segment-1",
+		PortId:         "port-1",
+		ApiSegmentPort: helpers.ApiSegmentPort{Id: "port-1", Revision: &revision},
+	})
+	if !IsPreconditionFailed(err) {
+		t.Fatalf("expected a precondition failed error, got %v", err)
+	}
+}
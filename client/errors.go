@@ -0,0 +1,48 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by NewClient and GetDefaultHeaders. They are wrapped with
+// the underlying cause, so use errors.Is to check for them.
+var (
+	// ErrInvalidHost is returned when the NSX manager address can't be parsed.
+	ErrInvalidHost = errors.New("invalid NSX manager host")
+	// ErrUnreachable is returned when no connection could be made to the NSX
+	// manager.
+	ErrUnreachable = errors.New("unable to reach NSX manager")
+	// ErrTLS is returned when the TLS handshake with the NSX manager fails,
+	// for example because its certificate is not trusted.
+	ErrTLS = errors.New("TLS handshake with NSX manager failed")
+	// ErrAuthentication is returned when NSX rejects the credentials.
+	ErrAuthentication = errors.New("NSX manager rejected the credentials")
+	// ErrMissingXsrfToken is returned when session creation succeeds but NSX
+	// does not return an X-XSRF-TOKEN header.
+	ErrMissingXsrfToken = errors.New("NSX manager did not return an X-XSRF-TOKEN header")
+)
+
+// classifyTransportError wraps an error returned by the HTTP client as either
+// ErrTLS or ErrUnreachable.
+func classifyTransportError(err error) error {
+	var (
+		verificationErr *tls.CertificateVerificationError
+		unknownAuthErr  x509.UnknownAuthorityError
+		hostnameErr     x509.HostnameError
+		invalidErr      x509.CertificateInvalidError
+		recordErr       tls.RecordHeaderError
+		alertErr        tls.AlertError
+	)
+	if errors.As(err, &verificationErr) || errors.As(err, &unknownAuthErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.Is(err, ErrThumbprintMismatch) {
+		return fmt.Errorf("%w: %w", ErrTLS, err)
+	}
+	return fmt.Errorf("%w: %w", ErrUnreachable, err)
+}
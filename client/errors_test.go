@@ -0,0 +1,56 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewClientErrors(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(rejecting.Close)
+
+	noToken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "JSESSIONID=session; Path=/")
+	}))
+	t.Cleanup(noToken.Close)
+
+	noSession := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-XSRF-TOKEN", "token")
+	}))
+	t.Cleanup(noSession.Close)
+
+	untrusted := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(untrusted.Close)
+
+	tests := map[string]struct {
+		server string
+		want   error
+	}{
+		"invalid host":       {server: "https://nsx manager", want: ErrInvalidHost},
+		"unsupported scheme": {server: "ftp://nsx.example.com", want: ErrInvalidHost},
+		"unreachable":        {server: closed.URL, want: ErrUnreachable},
+		"bad credentials":    {server: rejecting.URL, want: ErrAuthentication},
+		"missing xsrf token": {server: noToken.URL, want: ErrMissingXsrfToken},
+		"missing session":    {server: noSession.URL, want: ErrAuthentication},
+		"untrusted tls":      {server: untrusted.URL, want: ErrTLS},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewClient(context.Background(), tc.server, "admin", "secret", false, false)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
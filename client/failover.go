@@ -0,0 +1,164 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// healthCheckPath is probed on a manager node before failing over to it.
+	healthCheckPath = "/api/v1/reverse-proxy/node/health"
+	// healthCheckTimeout bounds each probe, so a node which accepts
+	// connections but never answers doesn't stall the failover.
+	healthCheckTimeout = 10 * time.Second
+)
+
+// WithFailoverServers adds NSX manager nodes which are used, in order, when
+// the current one can't be reached or returns 503 Service Unavailable. The
+// server passed to NewClient is tried first. Once the client has failed over
+// it stays on the new node until that node fails too.
+func WithFailoverServers(servers ...string) NsxClientOption {
+	return func(c *Client) error {
+		for _, server := range servers {
+			normalized, err := normalizeServer(server)
+			if err != nil {
+				return err
+			}
+			c.servers = append(c.servers, normalized)
+		}
+		return nil
+	}
+}
+
+// normalizeServer adds the default https scheme to server and checks that it
+// is a usable NSX manager address.
+func normalizeServer(server string) (string, error) {
+	svr := server
+	if !strings.Contains(server, "://") {
+		svr = "https://" + server
+	}
+	s, err := url.Parse(svr)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrInvalidHost, server, err)
+	}
+	if (s.Scheme != "https" && s.Scheme != "http") || s.Host == "" {
+		return "", fmt.Errorf("%w %q: expected a hostname, IP address or http(s) URL", ErrInvalidHost, server)
+	}
+	return svr, nil
+}
+
+// currentServer returns the manager node requests are sent to.
+func (c *Client) currentServer() string {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.Server
+}
+
+// retarget points req at server, keeping its path and query.
+func retarget(req *http.Request, server string) error {
+	s, err := url.Parse(server)
+	if err != nil {
+		return err
+	}
+	req.URL.Scheme = s.Scheme
+	req.URL.Host = s.Host
+	req.Host = ""
+	return nil
+}
+
+// shouldFailover reports whether the outcome of a request means the manager
+// node is down. Both cases are safe to send to another node: a failed dial
+// never reached NSX, and NSX doesn't act on a request it answers with 503.
+func shouldFailover(resp *http.Response, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	return resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// isFailoverError reports whether an error returned by the login means the
+// manager node is down.
+func isFailoverError(err error) bool {
+	var apiErr *APIError
+	return errors.Is(err, ErrUnreachable) || (errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable)
+}
+
+// failover moves the client from the failed node to the next healthy one,
+// logging in there unless a client certificate is used. If another request
+// has already failed over, its choice is kept.
+func (c *Client) failover(ctx context.Context, failed string) error {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	if c.Server != failed {
+		return nil
+	}
+
+	start := 0
+	for i, server := range c.servers {
+		if server == failed {
+			start = i
+			break
+		}
+	}
+
+	var errs []error
+	for i := 1; i < len(c.servers); i++ {
+		candidate := c.servers[(start+i)%len(c.servers)]
+		if err := c.healthCheck(ctx, candidate); err != nil {
+			c.logger.Debug(ctx, "NSX manager failed the health check", map[string]any{"server": candidate, logFieldError: err.Error()})
+			errs = append(errs, fmt.Errorf("%s: %w", candidate, err))
+			continue
+		}
+
+		if !c.certificateAuth {
+			session, xsrfToken, err := c.login(ctx, candidate, c.username, c.password)
+			if err != nil {
+				c.logger.Debug(ctx, "Unable to log in to NSX manager", map[string]any{"server": candidate, logFieldError: err.Error()})
+				errs = append(errs, fmt.Errorf("%s: %w", candidate, err))
+				continue
+			}
+			c.Session = session
+			c.XsrfToken = xsrfToken
+		}
+
+		c.logger.Debug(ctx, "Failed over to another NSX manager", map[string]any{"from": failed, "server": candidate})
+		c.Server = candidate
+		return nil
+	}
+	return fmt.Errorf("%w: no other NSX manager is available: %w", ErrUnreachable, errors.Join(errs...))
+}
+
+// healthCheck probes a manager node. Any answer short of a server error shows
+// the node is up, since the probe is sent without credentials.
+func (c *Client) healthCheck(ctx context.Context, server string) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server+healthCheckPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set(RequestIDHeader, newRequestID())
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return classifyTransportError(err)
+	}
+	_ = closeResponse(resp)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
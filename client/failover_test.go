@@ -0,0 +1,94 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClientFailsOverWhenManagerIsUnreachable(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	up, logins := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+
+	c, err := NewClient(context.Background(), down.URL, "admin", "secret", false, false,
+		WithFailoverServers(up.URL),
+		WithRetryPolicy(testRetryPolicy()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if c.Server != up.URL || logins.Load() != 1 {
+		t.Errorf("expected to be logged in to %s, got server %s with %d logins", up.URL, c.Server, logins.Load())
+	}
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Errorf("GetSegmentPort returned error after failover: %s", err)
+	}
+}
+
+func TestClientFailsOverOnServiceUnavailableAndSticks(t *testing.T) {
+	var primaryDown atomic.Bool
+	var primaryCalls atomic.Int32
+	primary, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls.Add(1)
+		if primaryDown.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"id": "port-1", "display_name": "primary"}`)
+	})
+	secondary, secondaryLogins := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1", "display_name": "secondary"}`)
+	})
+
+	c, err := NewClient(context.Background(), primary.URL, "admin", "secret", false, false,
+		WithFailoverServers(secondary.URL),
+		WithRetryPolicy(testRetryPolicy()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	primaryDown.Store(true)
+	port, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1")
+	if err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+	if port.DisplayName != "secondary" || secondaryLogins.Load() != 1 {
+		t.Errorf("expected the request to be answered by the secondary after a new login, got %q with %d logins", port.DisplayName, secondaryLogins.Load())
+	}
+
+	// The primary has recovered, but the client stays on the node which works.
+	primaryDown.Store(false)
+	before := primaryCalls.Load()
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+	if primaryCalls.Load() != before {
+		t.Error("expected the client to stick with the secondary")
+	}
+}
+
+func TestClientFailoverReportsWhenNoManagerIsAvailable(t *testing.T) {
+	first := httptest.NewServer(http.NotFoundHandler())
+	first.Close()
+	second := httptest.NewServer(http.NotFoundHandler())
+	second.Close()
+
+	_, err := NewClient(context.Background(), first.URL, "admin", "secret", false, false,
+		WithFailoverServers(second.URL),
+		WithRetryPolicy(RetryPolicy{}),
+	)
+	if !errors.Is(err, ErrUnreachable) {
+		t.Errorf("expected ErrUnreachable, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// LogSubsystem is the tflog subsystem the client logs to. Its level can be set
+// on its own with the TF_LOG_PROVIDER_NSX_CLIENT environment variable.
+const LogSubsystem = "nsx_client"
+
+// RequestIDHeader carries the ID generated for every call, so that a request
+// in the Terraform log can be matched with the NSX manager's logs.
+const RequestIDHeader = "X-NSX-Requestid"
+
+// Structured log field names.
+const (
+	logFieldRequestID = "request_id"
+	logFieldMethod    = "method"
+	logFieldPath      = "path"
+	logFieldStatus    = "status"
+	logFieldLatency   = "latency_ms"
+	logFieldAttempt   = "attempt"
+	logFieldError     = "error"
+	logFieldSegmentID = "segment_id"
+	logFieldPortID    = "port_id"
+)
+
+// Logger receives the client's log output. The function signatures match
+// tflog, so the context carries the logger configured by Terraform.
+type Logger interface {
+	Debug(ctx context.Context, msg string, additionalFields ...map[string]any)
+	Error(ctx context.Context, msg string, additionalFields ...map[string]any)
+}
+
+// tflogLogger writes to the LogSubsystem subsystem of the provider logger in
+// ctx. When debug is set, debug messages are written whatever TF_LOG says.
+type tflogLogger struct {
+	debug bool
+}
+
+// subsystem adds the client's subsystem to ctx. Two frames are skipped so
+// that log lines point at the client code which logged them rather than at
+// this file or the redacting logger.
+func (l tflogLogger) subsystem(ctx context.Context) context.Context {
+	if l.debug {
+		return tflog.NewSubsystem(ctx, LogSubsystem,
+			tflog.WithLevel(hclog.Debug), tflog.WithRootFields(), tflog.WithAdditionalLocationOffset(2))
+	}
+	return tflog.NewSubsystem(ctx, LogSubsystem,
+		tflog.WithLevelFromEnv("TF_LOG_PROVIDER", LogSubsystem), tflog.WithRootFields(), tflog.WithAdditionalLocationOffset(2))
+}
+
+func (l tflogLogger) Debug(ctx context.Context, msg string, additionalFields ...map[string]any) {
+	tflog.SubsystemDebug(l.subsystem(ctx), LogSubsystem, msg, additionalFields...)
+}
+
+func (l tflogLogger) Error(ctx context.Context, msg string, additionalFields ...map[string]any) {
+	tflog.SubsystemError(l.subsystem(ctx), LogSubsystem, msg, additionalFields...)
+}
+
+// newRequestID returns a random version 4 UUID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]any
+}
+
+// testLogger records every message it receives.
+type testLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *testLogger) log(level string, msg string, additionalFields []map[string]any) {
+	fields := map[string]any{}
+	for _, f := range additionalFields {
+		for k, v := range f {
+			fields[k] = v
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *testLogger) Debug(_ context.Context, msg string, additionalFields ...map[string]any) {
+	l.log("debug", msg, additionalFields)
+}
+
+func (l *testLogger) Error(_ context.Context, msg string, additionalFields ...map[string]any) {
+	l.log("error", msg, additionalFields)
+}
+
+// String renders every entry, one per line, for searching and test output.
+func (l *testLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b strings.Builder
+	for _, e := range l.entries {
+		fmt.Fprintf(&b, "[%s] %s %v\n", e.level, e.msg, e.fields)
+	}
+	return b.String()
+}
+
+func TestClientLogsRequestsWithRequestID(t *testing.T) {
+	var requestID string
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(RequestIDHeader)
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+	logger := &testLogger{}
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+
+	if requestID == "" {
+		t.Fatalf("expected the request to carry %s", RequestIDHeader)
+	}
+	for _, e := range logger.entries {
+		if e.msg != "NSX API request" || e.fields[logFieldRequestID] != requestID {
+			continue
+		}
+		if e.fields[logFieldMethod] != http.MethodGet ||
+			e.fields[logFieldPath] != "/policy/api/v1/infra/segments/segment-1/ports/port-1" ||
+			e.fields[logFieldStatus] != http.StatusOK {
+			t.Errorf("unexpected request log fields: %v", e.fields)
+		}
+		if _, ok := e.fields[logFieldLatency].(int64); !ok {
+			t.Errorf("expected %s field, got %v", logFieldLatency, e.fields)
+		}
+		return
+	}
+	t.Errorf("no request log for request ID %s in:\n%s", requestID, logger)
+}
+
+func TestClientLogsToTflogSubsystem(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	c, err := NewClient(ctx, server.URL, "admin", "secret", false, true)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if _, err := c.GetSegmentPort(ctx, "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("failed to decode log output: %s", err)
+	}
+	for _, entry := range entries {
+		if entry["@message"] == "NSX API request" && entry["@module"] == "provider."+LogSubsystem && entry[logFieldRequestID] != "" {
+			return
+		}
+	}
+	t.Errorf("no request log in the %s subsystem: %v", LogSubsystem, entries)
+}
@@ -9,25 +9,33 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/http"
 	"net/url"
-	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"terraform-provider-nsx-intervlan-routing/helpers"
-
-	"github.com/sirupsen/logrus"
 )
 
 type NsxClientOption func(*Client) error
 
+// NsxClientInterface is the set of NSX operations used by the provider's
+// resources and data sources. Client implements it against a live NSX
+// manager; tests can substitute a fake.
 type NsxClientInterface interface {
-	DeleteSegmentPort(string) (*http.Response, error)
-	ListSegmentPorts(string) (*helpers.ListSegmentPortsResponse, error)
-	GetSegmentPort(string, string) (*helpers.SegmentPort, error)
-	PatchSegmentPort(string, string) (*bool, error)
+	DeleteSegmentPort(ctx context.Context, segmentId string, portId string, reqEditors ...RequestEditorFn) error
+	ListSegmentPorts(ctx context.Context, params helpers.ListSegmentPortsRequest, reqEditors ...RequestEditorFn) (*helpers.ListSegmentPortsResponse, error)
+	IterateSegmentPorts(ctx context.Context, params helpers.ListSegmentPortsRequest, reqEditors ...RequestEditorFn) iter.Seq2[helpers.ApiSegmentPort, error]
+	GetSegmentPort(ctx context.Context, segmentId string, portId string, reqEditors ...RequestEditorFn) (*helpers.ApiSegmentPort, error)
+	PatchSegmentPort(ctx context.Context, body helpers.PatchSegmentPortRequest, reqEditors ...RequestEditorFn) error
+	PutSegmentPort(ctx context.Context, body helpers.PatchSegmentPortRequest, reqEditors ...RequestEditorFn) (*helpers.ApiSegmentPort, error)
 }
 
+var _ NsxClientInterface = &Client{}
+
 // RequestEditorFn  is the function signature for the RequestEditor callback function.
 type RequestEditorFn func(ctx context.Context, req *http.Request) error
 
@@ -38,77 +46,165 @@ type HttpRequestDoer interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// defaultUserAgent is sent unless WithUserAgent is used.
+const defaultUserAgent = "Go-http-client/1.1"
+
+// sessionCookie is the cookie holding the NSX session created by login.
+const sessionCookie = "JSESSIONID"
+
 type Client struct {
 	Server         string
 	XsrfToken      string
 	Session        string
 	Client         HttpRequestDoer
 	RequestEditors []RequestEditorFn
-}
+	Retry          RetryPolicy
 
-func setupLogging(debug bool) {
-	var ll logrus.Level
-	if debug {
-		ll = logrus.DebugLevel
-	} else {
-		ll = logrus.InfoLevel
-	}
-	logrus.SetLevel(ll)
+	// servers lists every NSX manager node, starting with the one passed to
+	// NewClient. Server is the node currently in use.
+	servers []string
+
+	// username and password are kept so that an expired session can be
+	// re-established without involving the caller.
+	username string
+	password string
+
+	// tlsConfig is used to build the default transport. Options may change it
+	// before the transport is created.
+	tlsConfig *tls.Config
+
+	// certificateAuth is set when the client authenticates with a principal
+	// identity certificate instead of a session.
+	certificateAuth bool
+
+	// userAgent is sent with every request.
+	userAgent string
+
+	// timeout and transport configure the default http.Client.
+	timeout   time.Duration
+	transport http.RoundTripper
+
+	// requestsPerSecond and maxConcurrent limit the requests sent to NSX.
+	requestsPerSecond float64
+	maxConcurrent     int
+
+	// idleTimeout is how long the client may be idle before it logs out.
+	// idleMu guards idleTimer and active, the number of requests in flight.
+	idleTimeout time.Duration
+	idleMu      sync.Mutex
+	idleTimer   *time.Timer
+	active      int
+
+	logger Logger
+
+	// vcrMode and vcrCassette are set by WithVCR to record or replay the
+	// exchanges with NSX.
+	vcrMode     VCRMode
+	vcrCassette string
+
+	// sessionMu guards Server, Session and XsrfToken, which are replaced when
+	// NSX expires the session or the client fails over to another node.
+	sessionMu sync.RWMutex
 }
 
-func NewClient(server string, username string, password string, insecure bool, debug bool, opts ...NsxClientOption) (*Client, error) {
-	setupLogging(debug)
-	logrus.Debug("Creating new NSX API Client")
+// NewClient creates a client for the NSX manager at server and logs in with
+// username and password, unless a client certificate is configured. When debug
+// is set, the client's debug messages are logged whatever the Terraform log
+// level is. ctx is only used for the login.
+func NewClient(ctx context.Context, server string, username string, password string, insecure bool, debug bool, opts ...NsxClientOption) (*Client, error) {
 	// Ensure we have a scheme set for the endpoint.
-	s, e := url.Parse(server)
-	if e != nil {
-		logrus.Errorf("Error parsing server URL: %s, exiting", e)
-		panic(e)
-	}
-	var svr string
-	if s.Scheme == "" {
-		logrus.Debug("Using default https scheme for server")
-		svr = "https://" + server
+	svr, err := normalizeServer(server)
+	if err != nil {
+		return nil, err
 	}
 
 	// create a client with sane default values
-	client := Client{
-		Server: svr,
-	}
-	// mutate client and add all optional params
+	client := &Client{
+		Server:    svr,
+		servers:   []string{svr},
+		Retry:     DefaultRetryPolicy(),
+		username:  username,
+		password:  password,
+		tlsConfig: &tls.Config{InsecureSkipVerify: insecure},
+		userAgent: defaultUserAgent,
+		timeout:   DefaultTimeout,
+		logger:    tflogLogger{debug: debug},
+
+		requestsPerSecond: DefaultMaxRequestsPerSecond,
+		maxConcurrent:     DefaultMaxConcurrentRequests,
+	}
+	// mutate client and add all optional params. This happens before the
+	// login so that options control every request the client sends.
 	for _, o := range opts {
-		if err := o(&client); err != nil {
+		if err := o(client); err != nil {
 			return nil, err
 		}
 	}
+	// Whatever logger is used, secrets never reach it.
+	client.logger = newRedactingLogger(client.logger)
+	client.logger.Debug(ctx, "Creating new NSX API Client", map[string]any{"server": client.Server, "insecure": insecure})
 
 	// create httpClient, if not already present
-	logrus.Debugf("Insecure mode is %t. Setting TLSConfig.", insecure)
-	tr := &http.Transport{
-		TLSClientConfig:     &tls.Config{InsecureSkipVerify: insecure},
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100,
-	}
 	if client.Client == nil {
-		logrus.Debug("Client is not instantiated. Creating client.Client with the Transport configuration specified")
-		client.Client = &http.Client{Transport: tr}
+		transport := client.transport
+		if transport == nil {
+			transport = &http.Transport{
+				TLSClientConfig:     client.tlsConfig,
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 100,
+			}
+		}
+		client.Client = &http.Client{Transport: transport, Timeout: client.timeout}
+	}
+	client.Client = newRateLimitedDoer(client.Client, client.requestsPerSecond, client.maxConcurrent)
+	if client.vcrMode != "" {
+		client.logger.Debug(ctx, "VCR mode enabled", map[string]any{"mode": string(client.vcrMode), "cassette": client.vcrCassette})
+		doer, err := newVCRDoer(client.vcrMode, client.vcrCassette, client.Client)
+		if err != nil {
+			return nil, err
+		}
+		client.Client = doer
+	}
+
+	// Principal identities authenticate every request with the client
+	// certificate, so there is no session to create.
+	if client.certificateAuth {
+		client.logger.Debug(ctx, "Client created. Using client certificate authentication")
+		client.username = ""
+		client.password = ""
+		return client, nil
 	}
 
-	logrus.Debug("Client created. Calling GetDefaultHeaders function")
-	err := GetDefaultHeaders(&client, username, password)
+	err = GetDefaultHeaders(ctx, client, username, password)
+	if err != nil && len(client.servers) > 1 && isFailoverError(err) {
+		client.logger.Debug(ctx, "Unable to log in to the first NSX manager. Trying the others", map[string]any{"server": client.Server, logFieldError: err.Error()})
+		err = client.failover(ctx, client.Server)
+	}
 	if err != nil {
 		return nil, err
 	}
+	client.startIdleTimer()
 
-	return &client, nil
+	return client, nil
 }
 
-func GetDefaultHeaders(c *Client, username string, password string) error {
-	logrus.Debug("Starting the GetDefaultHeaders function call")
+// GetDefaultHeaders logs in to NSX and stores the session cookie and XSRF
+// token used by every following request.
+func GetDefaultHeaders(ctx context.Context, c *Client, username string, password string) error {
+	session, xsrfToken, err := c.login(ctx, c.Server, username, password)
+	if err != nil {
+		return err
+	}
+	c.Session = session
+	c.XsrfToken = xsrfToken
+	return nil
+}
+
+// login creates a session on server and returns its cookie and XSRF token.
+func (c *Client) login(ctx context.Context, server string, username string, password string) (string, string, error) {
 	XsrfToken := "X-XSRF-TOKEN"
 
-	path := c.Server + "/api/session/create"
-	logrus.Debugf("Session Create URI is %s", path)
+	path := server + "/api/session/create"
 
 	data := url.Values{}
 	data.Set("j_username", username)
@@ -117,69 +213,247 @@ func GetDefaultHeaders(c *Client, username string, password string) error {
 	body := bytes.NewBufferString(data.Encode())
 
 	// Call session create
-	req, err := http.NewRequest(http.MethodPost, path, body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, body)
 	if err != nil {
-		logrus.Debugf("Failed to create session %s", err)
-		return err
+		return "", "", err
 	}
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set(RequestIDHeader, newRequestID())
+	for _, edit := range c.RequestEditors {
+		if err := edit(ctx, req); err != nil {
+			return "", "", err
+		}
+	}
 
+	fields := map[string]any{
+		logFieldRequestID: req.Header.Get(RequestIDHeader),
+		logFieldMethod:    req.Method,
+		logFieldPath:      req.URL.Path,
+	}
+	start := time.Now()
 	response, err := c.Client.Do(req)
-	if err != nil || response == nil {
-		logrus.Debugf("Failed to create session %s", err)
-		return err
+	fields[logFieldLatency] = time.Since(start).Milliseconds()
+	if err != nil {
+		fields[logFieldError] = err.Error()
+		c.logger.Error(ctx, "Failed to create NSX session", fields)
+		return "", "", classifyTransportError(err)
 	}
-	logrus.Debugf("Response header is %s", response.Header)
+	fields[logFieldStatus] = response.StatusCode
+	c.logger.Debug(ctx, "NSX session create responded", fields, map[string]any{"response_headers": redactHeader(response.Header)})
 
+	if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden {
+		return "", "", fmt.Errorf("%w: %w", ErrAuthentication, parseAPIError(response))
+	}
 	if response.StatusCode != 200 {
-		logrus.Debugf("Request responded with a non-200 status code. Code is: %d", response.StatusCode)
-		return fmt.Errorf("status code %d", response.StatusCode)
+		return "", "", parseAPIError(response)
 	}
 
-	// Go over the headers
-	for k, v := range response.Header {
-		if strings.EqualFold("Set-Cookie", k) {
-			r, _ := regexp.Compile("JSESSIONID=.*?;")
-			result := r.FindString(v[0])
-			if result != "" {
-				c.Session = result
-			}
-		}
-		if strings.EqualFold(XsrfToken, k) {
-			c.XsrfToken = v[0]
+	// The session cookie may be any of the cookies set, in any position.
+	var session string
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == sessionCookie && cookie.Value != "" {
+			session = sessionCookie + "=" + cookie.Value + ";"
 		}
 	}
+	xsrfToken := response.Header.Get(XsrfToken)
 
 	err = response.Body.Close()
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	logrus.Debug("Successfully completed the GetDefaultHeaders function call")
-	return nil
+	if session == "" {
+		// Without a session every request would log in again, leaving a
+		// session behind on the manager each time.
+		return "", "", fmt.Errorf("%w: NSX did not return a %s session cookie", ErrAuthentication, sessionCookie)
+	}
+	if xsrfToken == "" {
+		return "", "", ErrMissingXsrfToken
+	}
+	return session, xsrfToken, nil
+}
+
+// sessionHeaders returns the current session cookie and XSRF token.
+func (c *Client) sessionHeaders() (string, string) {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.Session, c.XsrfToken
+}
+
+// reauthenticate creates a new NSX session to replace staleSession. If another
+// request has already replaced it while we were waiting for the lock, the
+// session is reused rather than logging in a second time.
+func (c *Client) reauthenticate(ctx context.Context, staleSession string) error {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	if c.Session != staleSession {
+		c.logger.Debug(ctx, "Session was already refreshed by another request")
+		return nil
+	}
+
+	c.logger.Debug(ctx, "NSX session has expired. Creating a new session")
+	return GetDefaultHeaders(ctx, c, c.username, c.password)
+}
+
+// isSessionExpired reports whether NSX rejected the request because the
+// session cookie or XSRF token is no longer valid.
+func isSessionExpired(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden
+}
+
+// do sends req to the current manager node with the default headers applied
+// and a new request ID, which is kept if the request is retried. Transient
+// failures are retried according to the client's retry policy. If the node is
+// down and other nodes are configured, the client fails over and the request
+// is sent again to the new node. If NSX reports that the session has expired,
+// the client logs in again and the request is replayed once with the new
+// session. Responses outside the 2xx range are returned as an *APIError.
+func (c *Client) do(ctx context.Context, req *http.Request, reqEditors []RequestEditorFn) (*http.Response, error) {
+	c.beginRequest()
+	defer c.endRequest()
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, fmt.Errorf("failed to log in to NSX again: %w", err)
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set(RequestIDHeader, newRequestID())
+	server := c.currentServer()
+	if err := retarget(req, server); err != nil {
+		return nil, err
+	}
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(ctx, req)
+
+	if len(c.servers) > 1 && shouldFailover(resp, err) {
+		if ferr := c.failover(ctx, server); ferr != nil {
+			c.logger.Error(ctx, "Unable to fail over to another NSX manager", map[string]any{
+				logFieldRequestID: req.Header.Get(RequestIDHeader),
+				logFieldError:     ferr.Error(),
+			})
+		} else {
+			if resp != nil {
+				_ = closeResponse(resp)
+			}
+			if req, err = c.resend(ctx, req, reqEditors); err != nil {
+				return nil, err
+			}
+			resp, err = c.send(ctx, req)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if isSessionExpired(resp) && c.username != "" {
+		c.logger.Debug(ctx, "NSX rejected the session. Refreshing the session", map[string]any{
+			logFieldRequestID: req.Header.Get(RequestIDHeader),
+			logFieldStatus:    resp.StatusCode,
+		})
+		_ = resp.Body.Close()
+
+		if err := c.reauthenticate(ctx, req.Header.Get("Cookie")); err != nil {
+			return nil, fmt.Errorf("failed to refresh expired NSX session: %w", err)
+		}
+
+		if req, err = c.resend(ctx, req, reqEditors); err != nil {
+			return nil, err
+		}
+		resp, err = c.send(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// resend prepares a copy of req to be sent again to the current manager node
+// with the current session.
+func (c *Client) resend(ctx context.Context, req *http.Request, reqEditors []RequestEditorFn) (*http.Request, error) {
+	retry, err := rewindRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := retarget(retry, c.currentServer()); err != nil {
+		return nil, err
+	}
+	if err := c.applyEditors(ctx, retry, reqEditors); err != nil {
+		return nil, err
+	}
+	return retry, nil
 }
 
 // WithHTTPClient allows overriding the default Doer, which is
-// automatically created using http.Client. This is useful for tests.
-//func WithHTTPClient(doer HttpRequestDoer) NsxClientOption {
-//	return func(c *Client) error {
-//		c.Client = doer
-//		return nil
-//	}
-//}
+// automatically created using http.Client. This is useful for tests. TLS,
+// transport and timeout options have no effect on a Doer set this way.
+func WithHTTPClient(doer HttpRequestDoer) NsxClientOption {
+	return func(c *Client) error {
+		c.Client = doer
+		return nil
+	}
+}
 
 // WithRequestEditorFn allows setting up a callback function, which will be
 // called right before sending the request. This can be used to mutate the request.
-//func WithRequestEditorFn(fn RequestEditorFn) NsxClientOption {
-//	return func(c *Client) error {
-//		c.RequestEditors = append(c.RequestEditors, fn)
-//		return nil
-//	}
-//}
+func WithRequestEditorFn(fn RequestEditorFn) NsxClientOption {
+	return func(c *Client) error {
+		c.RequestEditors = append(c.RequestEditors, fn)
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request, including
+// the login.
+func WithUserAgent(userAgent string) NsxClientOption {
+	return func(c *Client) error {
+		c.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithTimeout limits how long a single HTTP request to NSX may take,
+// including reading the response body. Retries each get the full timeout.
+// It defaults to DefaultTimeout, and zero means no timeout. A deadline on the
+// context passed to an operation also applies, and bounds the retries too.
+func WithTimeout(timeout time.Duration) NsxClientOption {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout must not be negative, got %s", timeout)
+		}
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithTransport replaces the transport of the default http.Client. The TLS
+// options are not applied to a transport set this way.
+func WithTransport(transport http.RoundTripper) NsxClientOption {
+	return func(c *Client) error {
+		c.transport = transport
+		return nil
+	}
+}
+
+// WithLogger sends the client's log output to logger instead of the
+// nsx_client tflog subsystem. The debug argument of NewClient has no effect on
+// a logger set this way.
+func WithLogger(logger Logger) NsxClientOption {
+	return func(c *Client) error {
+		c.logger = logger
+		return nil
+	}
+}
 
 func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
-	logrus.Debugf("RequestEditors are: %v", c.RequestEditors)
 	for _, r := range c.RequestEditors {
 		if err := r(ctx, req); err != nil {
 			return err
@@ -193,51 +467,46 @@ func (c *Client) applyEditors(ctx context.Context, req *http.Request, additional
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-	req.Header.Add("User-Agent", "Go-http-client/1.1")
+	req.Header.Set("User-Agent", c.userAgent)
 
-	req.Header.Add("Set-Cookie", c.Session)
-	req.Header.Add("cookie", c.Session)
-	req.Header.Add("X-XSRF-TOKEN", c.XsrfToken)
+	session, xsrfToken := c.sessionHeaders()
+	if session != "" {
+		req.Header.Set("Set-Cookie", session)
+		req.Header.Set("Cookie", session)
+	}
+	if xsrfToken != "" {
+		req.Header.Set("X-XSRF-TOKEN", xsrfToken)
+	}
 
-	logrus.Debugf("Completed the applyEditors function call. Request headers are: %v", req.Header)
+	c.logger.Debug(ctx, "Request headers", map[string]any{
+		logFieldRequestID: req.Header.Get(RequestIDHeader),
+		"request_headers": redactHeader(req.Header),
+	})
 	return nil
 }
 
-func (c *Client) DeleteSegmentPort(ctx context.Context, segmentId string, portId string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+func (c *Client) DeleteSegmentPort(ctx context.Context, segmentId string, portId string, reqEditors ...RequestEditorFn) error {
 	// Get the segment port first
-	pReq, err := c.GetSegmentPort(ctx, segmentId, portId, reqEditors...)
+	updatedSegmentPort, err := c.GetSegmentPort(ctx, segmentId, portId, reqEditors...)
 	if err != nil {
-		return nil, err
-	}
-	var updatedSegmentPort helpers.ApiSegmentPort
-	if err := json.NewDecoder(pReq.Body).Decode(&updatedSegmentPort); err != nil {
-		logrus.Debugf("Unable to decode httpResponse to ApiSegmentPort struct: %+v", pReq)
-		return nil, err
+		return wrapTimeout(ctx, "DeleteSegmentPort", segmentId, portId, err)
 	}
 
 	// If this is a CHILD port, just delete it.
 	if updatedSegmentPort.Attachment.Type == "CHILD" {
-		req, err := NewDeleteSegmentPortRequest(&c.Server, segmentId, portId)
+		server := c.currentServer()
+		req, err := NewDeleteSegmentPortRequest(&server, segmentId, portId)
 		if err != nil {
-			return nil, err
-		}
-
-		req = req.WithContext(ctx)
-		if err := c.applyEditors(ctx, req, reqEditors); err != nil {
-			return nil, err
+			return err
 		}
 
-		logrus.Debugf("Complete request is: %v", req)
-
-		resp, err := c.Client.Do(req)
+		resp, err := c.do(ctx, req, reqEditors)
 		if err != nil {
-			logrus.Errorf("Failed to delete segment port %s", err)
-			return nil, err
+			c.logger.Error(ctx, "Failed to delete segment port", map[string]any{logFieldError: err.Error()})
+			return wrapTimeout(ctx, "DeleteSegmentPort", segmentId, portId, err)
 		}
 
-		logrus.Debugf("DeleteSegmentPort response: %v", resp)
-
-		return resp, nil
+		return closeResponse(resp)
 	}
 
 	// Not a child port, so we can't delete it without reassigning the VM to another segment.
@@ -247,7 +516,7 @@ func (c *Client) DeleteSegmentPort(ctx context.Context, segmentId string, portId
 	patchPort := helpers.PatchSegmentPortRequest{
 		SegmentId:      segmentId,
 		PortId:         portId,
-		ApiSegmentPort: updatedSegmentPort,
+		ApiSegmentPort: *updatedSegmentPort,
 	}
 
 	return c.PatchSegmentPort(ctx, patchPort, reqEditors...)
@@ -258,102 +527,165 @@ func NewDeleteSegmentPortRequest(server *string, segmentId string, portId string
 
 	serverURL, err := url.Parse(*server)
 	if err != nil {
-		logrus.Errorf("Failed to parse the server %s", err)
 		return nil, err
 	}
 
 	operationPath := "/policy/api/v1/infra/segments/" + segmentId + "/ports/" + portId
 	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
-		logrus.Errorf("Failed to parse the full URL %s", err)
 		return nil, err
 	}
 
 	req, err := http.NewRequest(http.MethodDelete, queryURL.String(), nil)
 	if err != nil {
-		logrus.Errorf("Failed to delete the new http request %s", err)
 		return nil, err
 	}
 
-	logrus.Debugf("Created the request as %v", req)
 	return req, nil
 }
 
-func (c *Client) ListSegmentPorts(ctx context.Context, segmentId string, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	logrus.Debug(fmt.Sprintf("ListSegmentPorts called with segment ID: %s", segmentId))
-	req, err := NewListSegmentPortsRequest(&c.Server, segmentId)
-	if err != nil {
-		return nil, err
+// ListSegmentPorts returns every port on a segment, following the cursor
+// returned by NSX until all pages have been read.
+func (c *Client) ListSegmentPorts(ctx context.Context, params helpers.ListSegmentPortsRequest, reqEditors ...RequestEditorFn) (*helpers.ListSegmentPortsResponse, error) {
+	c.logger.Debug(ctx, "ListSegmentPorts called", map[string]any{logFieldSegmentID: params.SegmentId})
+
+	var segmentPorts helpers.ListSegmentPortsResponse
+	for {
+		page, err := c.ListSegmentPortsPage(ctx, params, reqEditors...)
+		if err != nil {
+			return nil, err
+		}
+
+		segmentPorts.Results = append(segmentPorts.Results, page.Results...)
+		segmentPorts.ResultCount = page.ResultCount
+		segmentPorts.SortBy = page.SortBy
+		segmentPorts.SortAscending = page.SortAscending
+
+		if !hasNextPage(params, page) {
+			return &segmentPorts, nil
+		}
+		params.Cursor = page.Cursor
 	}
+}
 
-	req = req.WithContext(ctx)
-	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
-		return nil, err
+// IterateSegmentPorts yields the ports on a segment one at a time, fetching
+// the next page only when the previous one has been consumed. Iteration stops
+// at the first error, which is yielded with an empty port.
+func (c *Client) IterateSegmentPorts(ctx context.Context, params helpers.ListSegmentPortsRequest, reqEditors ...RequestEditorFn) iter.Seq2[helpers.ApiSegmentPort, error] {
+	return func(yield func(helpers.ApiSegmentPort, error) bool) {
+		for {
+			page, err := c.ListSegmentPortsPage(ctx, params, reqEditors...)
+			if err != nil {
+				yield(helpers.ApiSegmentPort{}, err)
+				return
+			}
+
+			for _, port := range page.Results {
+				if !yield(port, nil) {
+					return
+				}
+			}
+
+			if !hasNextPage(params, page) {
+				return
+			}
+			params.Cursor = page.Cursor
+		}
 	}
+}
 
-	logrus.Debugf("Complete request is: %v", req)
+// hasNextPage reports whether NSX returned a cursor pointing past the page
+// which was just read. An empty page or a cursor which did not move ends the
+// listing, so a misbehaving manager can't cause an endless loop.
+func hasNextPage(params helpers.ListSegmentPortsRequest, page *helpers.ListSegmentPortsResponse) bool {
+	return page.Cursor != "" && page.Cursor != params.Cursor && len(page.Results) > 0
+}
 
-	resp, err := c.Client.Do(req)
+// ListSegmentPortsPage returns a single page of ports on a segment, starting
+// at params.Cursor.
+func (c *Client) ListSegmentPortsPage(ctx context.Context, params helpers.ListSegmentPortsRequest, reqEditors ...RequestEditorFn) (*helpers.ListSegmentPortsResponse, error) {
+	c.logger.Debug(ctx, "ListSegmentPortsPage called", map[string]any{logFieldSegmentID: params.SegmentId, "cursor": params.Cursor})
+	server := c.currentServer()
+	req, err := NewListSegmentPortsRequest(&server, params)
 	if err != nil {
-		logrus.Errorf("Failed to list segment ports %s", err)
 		return nil, err
 	}
 
-	logrus.Debugf("ListSegmentPorts response: %v", resp)
+	resp, err := c.do(ctx, req, reqEditors)
+	if err != nil {
+		c.logger.Error(ctx, "Failed to list segment ports", map[string]any{logFieldError: err.Error()})
+		return nil, wrapTimeout(ctx, "ListSegmentPorts", params.SegmentId, "", err)
+	}
 
-	return resp, nil
+	var segmentPorts helpers.ListSegmentPortsResponse
+	if err := decodeResponse(resp, &segmentPorts); err != nil {
+		return nil, wrapTimeout(ctx, "ListSegmentPorts", params.SegmentId, "", err)
+	}
+
+	return &segmentPorts, nil
 }
 
-func NewListSegmentPortsRequest(server *string, segmentId string) (*http.Request, error) {
+func NewListSegmentPortsRequest(server *string, params helpers.ListSegmentPortsRequest) (*http.Request, error) {
 	var err error
 
 	serverURL, err := url.Parse(*server)
 	if err != nil {
-		logrus.Errorf("Failed to parse the server %s", err)
 		return nil, err
 	}
 
-	operationPath := "/policy/api/v1/infra/segments/" + segmentId + "/ports"
+	operationPath := "/policy/api/v1/infra/segments/" + params.SegmentId + "/ports"
 	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
-		logrus.Errorf("Failed to parse the full URL %s", err)
 		return nil, err
 	}
 
+	query := queryURL.Query()
+	if params.Cursor != "" {
+		query.Set("cursor", params.Cursor)
+	}
+	if params.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(params.PageSize))
+	}
+	if params.SortBy != "" {
+		query.Set("sort_by", params.SortBy)
+	}
+	if params.SortAscending != nil {
+		query.Set("sort_ascending", strconv.FormatBool(*params.SortAscending))
+	}
+	if len(params.IncludedFields) > 0 {
+		query.Set("included_fields", strings.Join(params.IncludedFields, ","))
+	}
+	queryURL.RawQuery = query.Encode()
+
 	req, err := http.NewRequest(http.MethodGet, queryURL.String(), nil)
 	if err != nil {
-		logrus.Errorf("Failed to create the new http request %s", err)
 		return nil, err
 	}
 
-	logrus.Debugf("Created the request as %v", req)
 	return req, nil
 }
 
-func (c *Client) GetSegmentPort(ctx context.Context, segmentId string, portId string, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	logrus.Debug(fmt.Sprintf("GetSegmentPort called with segment ID: %s and Port ID: %s", segmentId, portId))
-	req, err := NewGetSegmentPortRequest(&c.Server, segmentId, portId)
+func (c *Client) GetSegmentPort(ctx context.Context, segmentId string, portId string, reqEditors ...RequestEditorFn) (*helpers.ApiSegmentPort, error) {
+	c.logger.Debug(ctx, "GetSegmentPort called", map[string]any{logFieldSegmentID: segmentId, logFieldPortID: portId})
+	server := c.currentServer()
+	req, err := NewGetSegmentPortRequest(&server, segmentId, portId)
 	if err != nil {
 		return nil, err
 	}
 
-	req = req.WithContext(ctx)
-	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
-		return nil, err
-	}
-
-	logrus.Debugf("Complete request is: %v", req)
-
-	resp, err := c.Client.Do(req)
+	resp, err := c.do(ctx, req, reqEditors)
 	if err != nil {
-		logrus.Errorf("Failed to get segment port %s", err)
-		return nil, err
+		c.logger.Error(ctx, "Failed to get segment port", map[string]any{logFieldError: err.Error()})
+		return nil, wrapTimeout(ctx, "GetSegmentPort", segmentId, portId, err)
 	}
 
-	logrus.Debugf("GetSegmentPort response: %v", resp)
-	logrus.Debugf("GetSegmentPort response body: %+v", resp.Body)
+	var segmentPort helpers.ApiSegmentPort
+	if err := decodeResponse(resp, &segmentPort); err != nil {
+		return nil, wrapTimeout(ctx, "GetSegmentPort", segmentId, portId, err)
+	}
+	c.logger.Debug(ctx, "GetSegmentPort response body", map[string]any{"segment_port": segmentPort})
 
-	return resp, nil
+	return &segmentPort, nil
 }
 
 func NewGetSegmentPortRequest(server *string, segmentId string, portId string) (*http.Request, error) {
@@ -361,136 +693,98 @@ func NewGetSegmentPortRequest(server *string, segmentId string, portId string) (
 
 	serverURL, err := url.Parse(*server)
 	if err != nil {
-		logrus.Errorf("Failed to parse the server %s", err)
 		return nil, err
 	}
 
 	operationPath := "/policy/api/v1/infra/segments/" + segmentId + "/ports/" + portId
 	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
-		logrus.Errorf("Failed to parse the full URL %s", err)
 		return nil, err
 	}
 
 	req, err := http.NewRequest(http.MethodGet, queryURL.String(), nil)
 	if err != nil {
-		logrus.Errorf("Failed to create the new http request %s", err)
 		return nil, err
 	}
 
-	logrus.Debugf("Created the request as %v", req)
 	return req, nil
 }
 
-func (c *Client) PatchSegmentPort(ctx context.Context, body helpers.PatchSegmentPortRequest, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	logrus.Debug(fmt.Sprintf("PatchSegmentPort called with segment ID: %s and Port ID: %s", body.SegmentId, body.PortId))
-	//req, err := NewPatchSegmentPortRequest(c.Server, body)
-	//if err != nil {
-	//	return nil, err
-	//}
-	serverURL, err := url.Parse(c.Server)
+func (c *Client) PatchSegmentPort(ctx context.Context, body helpers.PatchSegmentPortRequest, reqEditors ...RequestEditorFn) error {
+	c.logger.Debug(ctx, "PatchSegmentPort called", map[string]any{logFieldSegmentID: body.SegmentId, logFieldPortID: body.PortId})
+	serverURL, err := url.Parse(c.currentServer())
 	if err != nil {
-		logrus.Errorf("Failed to parse the server %s", err)
-		return nil, err
+		c.logger.Error(ctx, "Failed to parse the server", map[string]any{logFieldError: err.Error()})
+		return err
 	}
 
 	operationPath := "/policy/api/v1/infra/segments/" + body.SegmentId + "/ports/" + body.PortId
 	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
-		logrus.Errorf("Failed to parse the full URL %s", err)
-		return nil, err
+		c.logger.Error(ctx, "Failed to parse the full URL", map[string]any{logFieldError: err.Error()})
+		return err
 	}
 	jBody, err := json.Marshal(body.ApiSegmentPort)
 	if err != nil {
-		logrus.Errorf("Failed to marshal the json body to an io.Reader: %s", err)
-		return nil, err
+		c.logger.Error(ctx, "Failed to marshal the json body to an io.Reader", map[string]any{logFieldError: err.Error()})
+		return err
 	}
-	logrus.Debugf("Marshalled the body as %v", jBody)
+	c.logger.Debug(ctx, "Marshalled the request body", map[string]any{"body": string(jBody)})
 	bodyReader := bytes.NewBuffer(jBody)
 
 	req, err := http.NewRequest(http.MethodPatch, queryURL.String(), bodyReader)
 	if err != nil {
-		logrus.Errorf("Failed to create the new http request: %s", err)
-		return nil, err
-	}
-
-	logrus.Debugf("Created the request as %v", req)
-
-	req = req.WithContext(ctx)
-	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
-		return nil, err
-	}
-
-	logrus.Debugf("Complete request is: %v", req)
-
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
+		c.logger.Error(ctx, "Failed to create the new http request", map[string]any{logFieldError: err.Error()})
+		return err
 	}
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.do(ctx, req, reqEditors)
 	if err != nil {
-		logrus.Errorf("Failed to patch segment port %s", err)
-		return nil, err
+		c.logger.Error(ctx, "Failed to patch segment port", map[string]any{logFieldError: err.Error()})
+		return wrapTimeout(ctx, "PatchSegmentPort", body.SegmentId, body.PortId, err)
 	}
 
-	logrus.Debugf("PatchSegmentPort response: %v", resp)
-	logrus.Debugf("PatchSegmentPort response body: %+v", resp.Body)
-
-	return resp, nil
+	return closeResponse(resp)
 }
 
-func (c *Client) PutSegmentPort(ctx context.Context, body helpers.PatchSegmentPortRequest, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	logrus.Debug(fmt.Sprintf("PatchSegmentPort called with segment ID: %s and Port ID: %s", body.SegmentId, body.PortId))
-	//req, err := NewPatchSegmentPortRequest(c.Server, body)
-	//if err != nil {
-	//	return nil, err
-	//}
-	serverURL, err := url.Parse(c.Server)
+func (c *Client) PutSegmentPort(ctx context.Context, body helpers.PatchSegmentPortRequest, reqEditors ...RequestEditorFn) (*helpers.ApiSegmentPort, error) {
+	c.logger.Debug(ctx, "PutSegmentPort called", map[string]any{logFieldSegmentID: body.SegmentId, logFieldPortID: body.PortId})
+	serverURL, err := url.Parse(c.currentServer())
 	if err != nil {
-		logrus.Errorf("Failed to parse the server %s", err)
+		c.logger.Error(ctx, "Failed to parse the server", map[string]any{logFieldError: err.Error()})
 		return nil, err
 	}
 
 	operationPath := "/policy/api/v1/infra/segments/" + body.SegmentId + "/ports/" + body.PortId
 	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
-		logrus.Errorf("Failed to parse the full URL %s", err)
+		c.logger.Error(ctx, "Failed to parse the full URL", map[string]any{logFieldError: err.Error()})
 		return nil, err
 	}
 	jBody, err := json.Marshal(body.ApiSegmentPort)
 	if err != nil {
-		logrus.Errorf("Failed to marshal the json body to an io.Reader: %s", err)
+		c.logger.Error(ctx, "Failed to marshal the json body to an io.Reader", map[string]any{logFieldError: err.Error()})
 		return nil, err
 	}
-	logrus.Debugf("Marshalled the body as %v", jBody)
+	c.logger.Debug(ctx, "Marshalled the request body", map[string]any{"body": string(jBody)})
 	bodyReader := bytes.NewBuffer(jBody)
 
 	req, err := http.NewRequest(http.MethodPut, queryURL.String(), bodyReader)
 	if err != nil {
-		logrus.Errorf("Failed to create the new http request: %s", err)
+		c.logger.Error(ctx, "Failed to create the new http request", map[string]any{logFieldError: err.Error()})
 		return nil, err
 	}
 
-	logrus.Debugf("Created the request as %v", req)
-
-	req = req.WithContext(ctx)
-	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
-		return nil, err
-	}
-
-	logrus.Debugf("Complete request is: %v", req)
-
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	resp, err := c.Client.Do(req)
+	resp, err := c.do(ctx, req, reqEditors)
 	if err != nil {
-		logrus.Errorf("Failed to put segment port %s", err)
-		return nil, err
+		c.logger.Error(ctx, "Failed to put segment port", map[string]any{logFieldError: err.Error()})
+		return nil, wrapTimeout(ctx, "PutSegmentPort", body.SegmentId, body.PortId, err)
 	}
 
-	logrus.Debugf("PutSegmentPort response: %v", resp)
+	var segmentPort helpers.ApiSegmentPort
+	if err := decodeResponse(resp, &segmentPort); err != nil {
+		return nil, wrapTimeout(ctx, "PutSegmentPort", body.SegmentId, body.PortId, err)
+	}
 
-	return resp, nil
+	return &segmentPort, nil
 }
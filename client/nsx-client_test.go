@@ -0,0 +1,151 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"terraform-provider-nsx-intervlan-routing/helpers"
+)
+
+// newTestServer returns a server which hands out a new JSESSIONID on every
+// login and only accepts requests carrying the most recent one.
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	mux, logins := newTestMux(handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, logins
+}
+
+// newTestMux returns the handler behind newTestServer and its login counter.
+func newTestMux(handler http.HandlerFunc) (*http.ServeMux, *atomic.Int32) {
+	var logins atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/session/create", func(w http.ResponseWriter, r *http.Request) {
+		n := logins.Add(1)
+		w.Header().Set("Set-Cookie", fmt.Sprintf("JSESSIONID=session-%d; Path=/; HttpOnly", n))
+		w.Header().Set("X-XSRF-TOKEN", fmt.Sprintf("token-%d", n))
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/policy/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		current := fmt.Sprintf("JSESSIONID=session-%d;", logins.Load())
+		if r.Header.Get("Cookie") != current {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	})
+
+	return mux, &logins
+}
+
+func TestClientReauthenticatesExpiredSession(t *testing.T) {
+	server, logins := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1", "attachment": {"type": "CHILD"}}`)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	// Simulate NSX expiring the session by logging in behind the client's back.
+	logins.Add(1)
+
+	port, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1")
+	if err != nil {
+		t.Fatalf("GetSegmentPort returned error after re-authentication: %s", err)
+	}
+	if port.Id != "port-1" {
+		t.Errorf("expected port-1, got %q", port.Id)
+	}
+	if got := logins.Load(); got != 3 {
+		t.Errorf("expected 3 logins, got %d", got)
+	}
+	if c.Session != "JSESSIONID=session-3;" {
+		t.Errorf("expected client to hold the refreshed session, got %q", c.Session)
+	}
+}
+
+func TestClientLoginFindsSessionCookie(t *testing.T) {
+	var logins atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/session/create", func(w http.ResponseWriter, r *http.Request) {
+		logins.Add(1)
+		// The session cookie comes last and without attributes.
+		w.Header().Add("Set-Cookie", "LB=node-1; Path=/")
+		w.Header().Add("Set-Cookie", "JSESSIONID=session-1")
+		w.Header().Set("X-XSRF-TOKEN", "token-1")
+	})
+	mux.HandleFunc("/policy/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "JSESSIONID=session-1;" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	for range 3 {
+		if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+			t.Fatalf("GetSegmentPort returned error: %s", err)
+		}
+	}
+	if got := logins.Load(); got != 1 {
+		t.Errorf("expected the session to be reused, got %d logins", got)
+	}
+}
+
+func TestClientListSegmentPortsFollowsCursor(t *testing.T) {
+	pages := map[string]string{
+		"":  `{"results": [{"id": "port-1"}, {"id": "port-2"}], "result_count": 3, "cursor": "2"}`,
+		"2": `{"results": [{"id": "port-3"}], "result_count": 3}`,
+	}
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("page_size"); got != "2" {
+			t.Errorf("expected page_size=2, got %q", got)
+		}
+		_, _ = fmt.Fprint(w, pages[r.URL.Query().Get("cursor")])
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	params := helpers.ListSegmentPortsRequest{SegmentId: "segment-1", PageSize: 2}
+
+	ports, err := c.ListSegmentPorts(context.Background(), params)
+	if err != nil {
+		t.Fatalf("ListSegmentPorts returned error: %s", err)
+	}
+	if len(ports.Results) != 3 || ports.Results[2].Id != "port-3" {
+		t.Errorf("expected all 3 ports across both pages, got %+v", ports.Results)
+	}
+
+	var seen []string
+	for port, err := range c.IterateSegmentPorts(context.Background(), params) {
+		if err != nil {
+			t.Fatalf("IterateSegmentPorts returned error: %s", err)
+		}
+		seen = append(seen, port.Id)
+		if port.Id == "port-2" {
+			break
+		}
+	}
+	if len(seen) != 2 {
+		t.Errorf("expected iteration to stop after 2 ports, got %v", seen)
+	}
+}
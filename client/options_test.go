@@ -0,0 +1,109 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingTransport remembers the requests it forwards.
+type recordingTransport struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.requests = append(t.requests, req.Clone(req.Context()))
+	t.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientOptionsApplyBeforeLogin(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+	transport := &recordingTransport{}
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false,
+		WithTransport(transport),
+		WithUserAgent("terraform-provider-nsx-intervlan-routing/test"),
+		WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+			req.Header.Set("X-Test-Editor", "applied")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+
+	if len(transport.requests) != 2 {
+		t.Fatalf("expected the login and the GET to use the transport, got %d requests", len(transport.requests))
+	}
+	for _, req := range transport.requests {
+		if got := req.Header.Get("User-Agent"); got != "terraform-provider-nsx-intervlan-routing/test" {
+			t.Errorf("%s %s: expected custom User-Agent, got %q", req.Method, req.URL.Path, got)
+		}
+		if got := req.Header.Get("X-Test-Editor"); got != "applied" {
+			t.Errorf("%s %s: expected request editor to run, got %q", req.Method, req.URL.Path, got)
+		}
+	}
+}
+
+func TestClientWithHTTPClient(t *testing.T) {
+	server, logins := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	transport := &recordingTransport{}
+
+	_, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if logins.Load() != 1 || len(transport.requests) != 1 {
+		t.Errorf("expected the login to go through the injected client, got %d logins and %d requests", logins.Load(), len(transport.requests))
+	}
+}
+
+func TestClientWithTimeout(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false,
+		WithTimeout(20*time.Millisecond),
+		WithRetryPolicy(RetryPolicy{}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	_, err = c.GetSegmentPort(context.Background(), "segment-1", "port-1")
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+}
+
+func TestClientWithLogger(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	logger := &testLogger{}
+
+	if _, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithLogger(logger)); err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if !strings.Contains(logger.String(), "Creating new NSX API Client") {
+		t.Errorf("expected client logs in the injected logger, got:\n%s", logger.String())
+	}
+}
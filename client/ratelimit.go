@@ -0,0 +1,90 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// Client-side limits used unless WithRateLimit is used. They match the
+// per-client limits NSX Manager enforces by default, so a busy Terraform run
+// is slowed down by the client rather than rejected with 429.
+const (
+	DefaultMaxRequestsPerSecond  = 100
+	DefaultMaxConcurrentRequests = 40
+)
+
+// WithRateLimit limits the requests the client sends to NSX, including logins
+// and health checks. requestsPerSecond is enforced with a token bucket which
+// allows bursts of up to one second's worth of requests, and maxConcurrent
+// caps the requests in flight at once. Zero disables either limit. Every
+// resource and data source shares the provider's client, and so its limits.
+func WithRateLimit(requestsPerSecond float64, maxConcurrent int) NsxClientOption {
+	return func(c *Client) error {
+		if requestsPerSecond < 0 {
+			return fmt.Errorf("requests per second must not be negative, got %g", requestsPerSecond)
+		}
+		if maxConcurrent < 0 {
+			return fmt.Errorf("concurrent requests must not be negative, got %d", maxConcurrent)
+		}
+		c.requestsPerSecond = requestsPerSecond
+		c.maxConcurrent = maxConcurrent
+		return nil
+	}
+}
+
+// rateLimitedDoer holds each request until the token bucket and the
+// concurrency limit allow it to be sent.
+type rateLimitedDoer struct {
+	next HttpRequestDoer
+	// limiter is nil when the request rate is not limited.
+	limiter *rate.Limiter
+	// slots holds a token for every request in flight. It is nil when the
+	// concurrency is not limited.
+	slots chan struct{}
+}
+
+// newRateLimitedDoer wraps next with the given limits, or returns next when
+// both are disabled.
+func newRateLimitedDoer(next HttpRequestDoer, requestsPerSecond float64, maxConcurrent int) HttpRequestDoer {
+	if requestsPerSecond == 0 && maxConcurrent == 0 {
+		return next
+	}
+	d := &rateLimitedDoer{next: next}
+	if requestsPerSecond > 0 {
+		d.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), max(int(requestsPerSecond), 1))
+	}
+	if maxConcurrent > 0 {
+		d.slots = make(chan struct{}, maxConcurrent)
+	}
+	return d
+}
+
+// Do waits for a free slot and a token before sending req. The slot is
+// released once the response headers have arrived. Waiting honours the
+// request's context, and a wait which would outlast its deadline fails
+// straight away with context.DeadlineExceeded.
+func (d *rateLimitedDoer) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if d.slots != nil {
+		select {
+		case d.slots <- struct{}{}:
+			defer func() { <-d.slots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if d.limiter != nil {
+		if err := d.limiter.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("%w: %w", errWaitPastDeadline, err)
+		}
+	}
+	return d.next.Do(req)
+}
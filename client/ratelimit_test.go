@@ -0,0 +1,105 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientLimitsConcurrentRequests(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRateLimit(0, 2))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+				t.Errorf("GetSegmentPort returned error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("expected at most 2 requests in flight, saw %d", got)
+	}
+}
+
+func TestClientLimitsRequestRate(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+
+	// The login and the first 9 requests use the initial burst of 10 tokens,
+	// and the remaining 5 have to wait for new ones.
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRateLimit(10, 0))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	start := time.Now()
+	for range 14 {
+		if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+			t.Fatalf("GetSegmentPort returned error: %s", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected the requests to be throttled, took %s", elapsed)
+	}
+}
+
+func TestClientRateLimitWaitHonoursDeadline(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRateLimit(0.1, 0))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = c.GetSegmentPort(ctx, "segment-1", "port-1")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a timeout waiting for the rate limit, got %v", err)
+	}
+	if !timeoutErr.DeadlineExceeded {
+		t.Error("expected the wait to be reported as exceeding the caller's deadline")
+	}
+}
+
+func TestWithRateLimitRejectsNegativeValues(t *testing.T) {
+	for _, opt := range []NsxClientOption{WithRateLimit(-1, 0), WithRateLimit(0, -1)} {
+		if err := opt(&Client{}); err == nil {
+			t.Error("expected an error for a negative limit")
+		}
+	}
+}
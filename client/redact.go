@@ -0,0 +1,130 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces secrets in log output and VCR cassettes.
+const redacted = "REDACTED"
+
+// redactHeader returns a copy of header with session cookies, XSRF tokens and
+// credentials replaced. Cookies keep their name so that the session being used
+// can still be identified.
+func redactHeader(header http.Header) http.Header {
+	clean := header.Clone()
+	for name, values := range clean {
+		switch http.CanonicalHeaderKey(name) {
+		case "Cookie", "Set-Cookie":
+			for i, v := range values {
+				values[i] = redactCookie(v)
+			}
+		case "X-Xsrf-Token", "Authorization", "Proxy-Authorization":
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	return clean
+}
+
+// redactCookie replaces the value of every cookie in a Cookie or Set-Cookie
+// header, leaving the attributes that follow it alone.
+func redactCookie(value string) string {
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		name, _, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "path", "domain", "expires", "max-age", "samesite":
+			continue
+		}
+		parts[i] = name + "=" + redacted
+	}
+	return strings.Join(parts, ";")
+}
+
+// redactBody removes the login credentials from form-encoded bodies.
+func redactBody(contentType string, body []byte) string {
+	if !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return string(body)
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return redacted
+	}
+	for _, key := range []string{"j_username", "j_password"} {
+		if form.Has(key) {
+			form.Set(key, redacted)
+		}
+	}
+	return form.Encode()
+}
+
+// secretPatterns catch secrets in messages which were not redacted where they
+// were logged, such as headers printed as part of a request or an error.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(JSESSIONID=)[^;\s\]"&]+`),
+	regexp.MustCompile(`(?i)((?:X-XSRF-TOKEN|(?:Proxy-)?Authorization):\[)[^\]]*`),
+	regexp.MustCompile(`(?i)((?:X-XSRF-TOKEN|(?:Proxy-)?Authorization)[=:] ?)[^\s\[\]]+`),
+	regexp.MustCompile(`(j_password=)[^&\s"]+`),
+}
+
+// redactingLogger masks session cookies, XSRF tokens, Authorization headers
+// and login credentials in every message before passing it on. The password
+// itself is not searched for: it is only ever sent in the login body, which
+// is redacted field by field, and replacing every occurrence of a short
+// password would garble unrelated text and reveal where it appears.
+type redactingLogger struct {
+	next Logger
+}
+
+func newRedactingLogger(next Logger) Logger {
+	if l, ok := next.(*redactingLogger); ok {
+		next = l.next
+	}
+	return &redactingLogger{next: next}
+}
+
+func (l *redactingLogger) redact(msg string) string {
+	for _, p := range secretPatterns {
+		msg = p.ReplaceAllString(msg, "${1}"+redacted)
+	}
+	return msg
+}
+
+// redactFields returns a copy of fields with every value redacted. Values are
+// logged as strings so that secrets inside structs are caught too.
+func (l *redactingLogger) redactFields(fields []map[string]any) []map[string]any {
+	clean := make([]map[string]any, 0, len(fields))
+	for _, f := range fields {
+		m := make(map[string]any, len(f))
+		for k, v := range f {
+			switch v.(type) {
+			case bool, int, int32, int64, float64:
+				m[k] = v
+			default:
+				m[k] = l.redact(fmt.Sprint(v))
+			}
+		}
+		clean = append(clean, m)
+	}
+	return clean
+}
+
+func (l *redactingLogger) Debug(ctx context.Context, msg string, additionalFields ...map[string]any) {
+	l.next.Debug(ctx, l.redact(msg), l.redactFields(additionalFields)...)
+}
+
+func (l *redactingLogger) Error(ctx context.Context, msg string, additionalFields ...map[string]any) {
+	l.next.Error(ctx, l.redact(msg), l.redactFields(additionalFields)...)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDebugLogsNeverContainSecrets(t *testing.T) {
+	server, logins := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1", "attachment": {"type": "CHILD"}}`)
+	})
+
+	logger := &testLogger{}
+
+	password := "p@ss word&1"
+	c, err := NewClient(context.Background(), server.URL, "admin", password, false, true,
+		WithLogger(logger),
+		WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer editor-token")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	// Force a re-authentication so the refreshed session is logged as well.
+	logins.Add(1)
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+	// Log a raw header directly, as an error message might.
+	c.logger.Debug(context.Background(), fmt.Sprintf("unexpected headers %v and body j_password=%s", http.Header{"X-Xsrf-Token": {"token-9"}, "Cookie": {"JSESSIONID=session-9;"}}, password),
+		map[string]any{"headers": http.Header{"Cookie": {"JSESSIONID=session-8;"}}})
+
+	logs := logger.String()
+	if !strings.Contains(logs, "Request headers") {
+		t.Fatalf("expected debug logs to be written, got:\n%s", logs)
+	}
+	secrets := []string{password, "p%40ss", "editor-token"}
+	for i := 1; i <= 9; i++ {
+		secrets = append(secrets, fmt.Sprintf("session-%d", i), fmt.Sprintf("token-%d", i))
+	}
+	for _, secret := range secrets {
+		if strings.Contains(logs, secret) {
+			t.Errorf("debug logs contain secret %q", secret)
+		}
+	}
+}
+
+func TestDebugLogsKeepTextMatchingShortPassword(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+
+	logger := &testLogger{}
+	c, err := NewClient(context.Background(), server.URL, "admin", "a", false, true, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+
+	logs := logger.String()
+	if !strings.Contains(logs, "GetSegmentPort called") || !strings.Contains(logs, "segment-1") {
+		t.Errorf("expected log messages to be left intact, got:\n%s", logs)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	got := redactBody("application/x-www-form-urlencoded", []byte("j_username=admin&j_password=secret"))
+	if strings.Contains(got, "secret") || strings.Contains(got, "admin") {
+		t.Errorf("expected credentials to be redacted, got %q", got)
+	}
+}
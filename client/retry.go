@@ -0,0 +1,232 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// nsxErrorCodeServiceBusy is returned by NSX when the manager is too busy to
+// process the request. The request has not been applied, so it is always safe
+// to send it again.
+const nsxErrorCodeServiceBusy = 98
+
+// RetryPolicy controls how the client retries requests which failed with a
+// transient error.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried after the first
+	// attempt. Zero disables retries.
+	MaxRetries int
+	// MinDelay is the backoff used before the first retry. It doubles on every
+	// subsequent retry.
+	MinDelay time.Duration
+	// MaxDelay caps the exponential backoff. A Retry-After value sent by NSX
+	// is always honoured in full.
+	MaxDelay time.Duration
+	// RetryOnStatusCodes lists the HTTP status codes which are retried.
+	RetryOnStatusCodes []int
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:         4,
+		MinDelay:           500 * time.Millisecond,
+		MaxDelay:           5 * time.Second,
+		RetryOnStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	}
+}
+
+// WithRetryPolicy overrides the default retry policy.
+func WithRetryPolicy(policy RetryPolicy) NsxClientOption {
+	return func(c *Client) error {
+		c.Retry = policy
+		return nil
+	}
+}
+
+// isIdempotent reports whether sending the request twice has the same effect
+// as sending it once, even if the first attempt reached NSX.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRejectedStatus reports whether NSX rejected the request without
+// processing it, which makes a retry safe for any method.
+func isRejectedStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// shouldRetry decides whether the outcome of an attempt should be retried.
+// PATCH and PUT are only retried when NSX did not act on the request: it was
+// rate limited, the service was busy, or the connection was never made.
+func (p RetryPolicy) shouldRetry(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		if !isIdempotent(method) {
+			return false
+		}
+		return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+	}
+
+	if responseErrorCode(resp) == nsxErrorCodeServiceBusy {
+		return true
+	}
+	if !slices.Contains(p.RetryOnStatusCodes, resp.StatusCode) {
+		return false
+	}
+	return isIdempotent(method) || isRejectedStatus(resp.StatusCode)
+}
+
+// backoff returns how long to wait before the given retry, which starts at
+// zero, and whether the wait was requested by NSX. A Retry-After header takes
+// precedence over the computed exponential backoff and is not capped by
+// MaxDelay, since retrying earlier would only be throttled again.
+func (p RetryPolicy) backoff(retry int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return wait, true
+		}
+	}
+
+	delay := p.MinDelay << retry
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0, false
+	}
+
+	// Full jitter over the upper half of the window, so that parallel
+	// Terraform operations don't retry in lockstep.
+	half := delay / 2
+	return half + rand.N(delay-half+1), false
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// responseErrorCode returns the NSX error_code in an error response body, or
+// zero if there isn't one. The body is restored so that it can be read again.
+func responseErrorCode(resp *http.Response) int {
+	if resp.StatusCode < http.StatusBadRequest || resp.Body == nil {
+		return 0
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 0
+	}
+
+	var apiErr APIError
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		return 0
+	}
+	return apiErr.ErrorCode
+}
+
+// rewindRequest returns a copy of req with a fresh body so that it can be
+// sent again.
+func rewindRequest(ctx context.Context, req *http.Request) (*http.Request, error) {
+	clone := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// send performs req, retrying transient failures according to the client's
+// retry policy.
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	attempt := req
+	for retry := 0; ; retry++ {
+		fields := map[string]any{
+			logFieldRequestID: req.Header.Get(RequestIDHeader),
+			logFieldMethod:    req.Method,
+			logFieldPath:      req.URL.Path,
+			logFieldAttempt:   retry + 1,
+		}
+		start := time.Now()
+		resp, err := c.Client.Do(attempt)
+		fields[logFieldLatency] = time.Since(start).Milliseconds()
+		if err != nil {
+			fields[logFieldError] = err.Error()
+		} else {
+			fields[logFieldStatus] = resp.StatusCode
+		}
+		c.logger.Debug(ctx, "NSX API request", fields)
+
+		if retry >= c.Retry.MaxRetries || !c.Retry.shouldRetry(req.Method, resp, err) {
+			return resp, err
+		}
+
+		wait, requested := c.Retry.backoff(retry, resp)
+		// When NSX asks for a wait which outlasts the deadline, return its
+		// response, e.g. a 429, rather than waiting only to time out.
+		if deadline, ok := ctx.Deadline(); ok && requested && time.Until(deadline) < wait {
+			c.logger.Debug(ctx, "Not retrying NSX API request, the wait would exceed the deadline", map[string]any{
+				logFieldRequestID: req.Header.Get(RequestIDHeader),
+				"retry_in_ms":     wait.Milliseconds(),
+			})
+			return resp, err
+		}
+		c.logger.Debug(ctx, "Retrying NSX API request", map[string]any{
+			logFieldRequestID: req.Header.Get(RequestIDHeader),
+			"retry_in_ms":     wait.Milliseconds(),
+		})
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		attempt, err = rewindRequest(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
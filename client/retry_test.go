@@ -0,0 +1,153 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"terraform-provider-nsx-intervlan-routing/helpers"
+)
+
+func testRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MinDelay = time.Millisecond
+	policy.MaxDelay = 10 * time.Millisecond
+	return policy
+}
+
+func TestClientRetriesRateLimitedRequests(t *testing.T) {
+	var calls atomic.Int32
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRetryPolicy(testRetryPolicy()))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error after retries: %s", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestClientHonoursRetryAfterLongerThanMaxDelay(t *testing.T) {
+	var calls atomic.Int32
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRetryPolicy(testRetryPolicy()))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	start := time.Now()
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error after retry: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the retry to wait for Retry-After, took %s", elapsed)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestClientReturnsRateLimitWhenRetryAfterExceedsDeadline(t *testing.T) {
+	var calls atomic.Int32
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRetryPolicy(testRetryPolicy()))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err = c.GetSegmentPort(ctx, "segment-1", "port-1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected a 429 APIError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the 429 to be returned without waiting, took %s", elapsed)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+func TestClientRetriesServiceBusyErrorCode(t *testing.T) {
+	var calls atomic.Int32
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprint(w, `{"error_code": 98, "error_message": "Service busy"}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRetryPolicy(testRetryPolicy()))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	err = c.PatchSegmentPort(context.Background(), helpers.PatchSegmentPortRequest{SegmentId: "segment-1", PortId: "port-1"})
+	if err != nil {
+		t.Fatalf("PatchSegmentPort returned error after retry: %s", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestClientDoesNotRetryUnsafePatch(t *testing.T) {
+	var calls atomic.Int32
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	policy := testRetryPolicy()
+	policy.RetryOnStatusCodes = append(policy.RetryOnStatusCodes, http.StatusBadGateway)
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	err = c.PatchSegmentPort(context.Background(), helpers.PatchSegmentPortRequest{SegmentId: "segment-1", PortId: "port-1"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected a 502 APIError, got %v", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected PATCH to be sent once, got %d attempts", got)
+	}
+}
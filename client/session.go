@@ -0,0 +1,165 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// logoutTimeout bounds the logout sent by Close, which has no caller context.
+const logoutTimeout = 10 * time.Second
+
+// WithIdleLogout logs the client out of NSX once no request has been sent for
+// the given period, so that an idle provider doesn't hold one of the sessions
+// NSX allows each user. The next request logs in again. Zero, the default,
+// keeps the session until Close is called.
+func WithIdleLogout(idle time.Duration) NsxClientOption {
+	return func(c *Client) error {
+		if idle < 0 {
+			return fmt.Errorf("idle logout period must not be negative, got %s", idle)
+		}
+		c.idleTimeout = idle
+		return nil
+	}
+}
+
+// Close logs out of the NSX session, freeing it on the manager. The client
+// can still be used afterwards and logs in again when it next sends a
+// request. Close does nothing for clients using certificate authentication.
+func (c *Client) Close() error {
+	c.idleMu.Lock()
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
+	c.idleMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), logoutTimeout)
+	defer cancel()
+	return c.logout(ctx)
+}
+
+// logout destroys the current session on the manager. The session is
+// forgotten even if NSX can't be reached, since it expires there on its own.
+func (c *Client) logout(ctx context.Context) error {
+	c.sessionMu.Lock()
+	server, session, xsrfToken := c.Server, c.Session, c.XsrfToken
+	c.Session = ""
+	c.XsrfToken = ""
+	c.sessionMu.Unlock()
+
+	if session == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+"/api/session/destroy", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Cookie", session)
+	req.Header.Set("X-XSRF-TOKEN", xsrfToken)
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set(RequestIDHeader, newRequestID())
+	for _, edit := range c.RequestEditors {
+		if err := edit(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	fields := map[string]any{
+		logFieldRequestID: req.Header.Get(RequestIDHeader),
+		logFieldMethod:    req.Method,
+		logFieldPath:      req.URL.Path,
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		fields[logFieldError] = err.Error()
+		c.logger.Error(ctx, "Failed to destroy NSX session", fields)
+		return classifyTransportError(err)
+	}
+	fields[logFieldStatus] = resp.StatusCode
+	c.logger.Debug(ctx, "NSX session destroy responded", fields)
+
+	if err := checkResponse(resp); err != nil {
+		return fmt.Errorf("failed to destroy NSX session: %w", err)
+	}
+	return closeResponse(resp)
+}
+
+// ensureSession logs in again if the session was closed by Close or by the
+// idle logout. Clients without credentials have no session to restore.
+func (c *Client) ensureSession(ctx context.Context) error {
+	if c.username == "" {
+		return nil
+	}
+	if session, _ := c.sessionHeaders(); session != "" {
+		return nil
+	}
+	return c.reauthenticate(ctx, "")
+}
+
+// beginRequest stops the idle logout timer while a request is in flight.
+func (c *Client) beginRequest() {
+	if c.idleTimeout == 0 {
+		return
+	}
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+	c.active++
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
+}
+
+// endRequest restarts the idle logout timer once no request is in flight.
+func (c *Client) endRequest() {
+	if c.idleTimeout == 0 {
+		return
+	}
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+	c.active--
+	if c.active == 0 {
+		c.resetIdleTimer()
+	}
+}
+
+// startIdleTimer starts the idle logout timer after the first login.
+func (c *Client) startIdleTimer() {
+	if c.idleTimeout == 0 {
+		return
+	}
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+	c.resetIdleTimer()
+}
+
+// resetIdleTimer (re)starts the idle logout timer. idleMu must be held.
+func (c *Client) resetIdleTimer() {
+	if c.idleTimer == nil {
+		c.idleTimer = time.AfterFunc(c.idleTimeout, c.idleLogout)
+		return
+	}
+	c.idleTimer.Reset(c.idleTimeout)
+}
+
+// idleLogout is run by the idle timer. A request which started just as the
+// timer fired keeps the session.
+func (c *Client) idleLogout() {
+	c.idleMu.Lock()
+	active := c.active
+	c.idleMu.Unlock()
+	if active > 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), logoutTimeout)
+	defer cancel()
+	c.logger.Debug(ctx, "NSX client is idle. Logging out", map[string]any{"idle_timeout": c.idleTimeout.String()})
+	if err := c.logout(ctx); err != nil {
+		c.logger.Error(ctx, "Failed to log out idle NSX session", map[string]any{logFieldError: err.Error()})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newLogoutTestServer is newTestServer with a session destroy endpoint which
+// counts logouts.
+func newLogoutTestServer(t *testing.T) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+
+	mux, logins := newTestMux(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+	var logouts atomic.Int32
+	mux.HandleFunc("/api/session/destroy", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Cookie") != fmt.Sprintf("JSESSIONID=session-%d;", logins.Load()) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		logouts.Add(1)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, logins, &logouts
+}
+
+func TestClientCloseLogsOut(t *testing.T) {
+	server, _, logouts := newLogoutTestServer(t)
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close returned error: %s", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close returned error: %s", err)
+	}
+	if got := logouts.Load(); got != 1 {
+		t.Errorf("expected 1 logout, got %d", got)
+	}
+}
+
+func TestClientIdleLogout(t *testing.T) {
+	server, logins, logouts := newLogoutTestServer(t)
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithIdleLogout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for logouts.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if logouts.Load() != 1 {
+		t.Fatal("expected the idle client to log out")
+	}
+
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error after idle logout: %s", err)
+	}
+	if got := logins.Load(); got != 2 {
+		t.Errorf("expected the client to log in again, got %d logins", got)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultTimeout is how long a single HTTP request to NSX may take unless
+// WithTimeout is used.
+const DefaultTimeout = 60 * time.Second
+
+// errWaitPastDeadline is returned when a request is not sent because waiting
+// for its turn would outlast the caller's context deadline.
+var errWaitPastDeadline = fmt.Errorf("%w: waiting to send the request would exceed the deadline", context.DeadlineExceeded)
+
+// ErrTimeout is matched by errors returned when a request to NSX did not
+// complete in time, either because the client's timeout expired or because
+// the caller's context deadline passed.
+var ErrTimeout = errors.New("NSX API request timed out")
+
+// TimeoutError is returned by the segment port operations when NSX did not
+// answer in time. It names the operation and the port so that the caller can
+// report which call was slow.
+type TimeoutError struct {
+	// Operation is the client method which timed out, e.g. GetSegmentPort.
+	Operation string
+	SegmentID string
+	// PortID is empty for operations on the whole segment.
+	PortID string
+	// DeadlineExceeded is true when the caller's context deadline passed, and
+	// false when the client's own request timeout expired. The two can't be
+	// told apart from Err, which matches context.DeadlineExceeded in both
+	// cases.
+	DeadlineExceeded bool
+	Err              error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.PortID == "" {
+		return fmt.Sprintf("%s on segment %s timed out: %s", e.Operation, e.SegmentID, e.Err)
+	}
+	return fmt.Sprintf("%s of port %s on segment %s timed out: %s", e.Operation, e.PortID, e.SegmentID, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrTimeout) true for every TimeoutError.
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// isTimeout reports whether err was caused by a deadline, either the
+// context's or the http.Client's.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// wrapTimeout returns err as a *TimeoutError for operation if it was caused by
+// a deadline. ctx is the operation's context, used to tell whether its
+// deadline or the request timeout expired. Other errors, and errors already
+// wrapped by a nested operation, are returned unchanged.
+func wrapTimeout(ctx context.Context, operation string, segmentId string, portId string, err error) error {
+	var timeoutErr *TimeoutError
+	if err == nil || !isTimeout(err) || errors.As(err, &timeoutErr) {
+		return err
+	}
+	return &TimeoutError{
+		Operation:        operation,
+		SegmentID:        segmentId,
+		PortID:           portId,
+		DeadlineExceeded: ctx.Err() != nil || errors.Is(err, errWaitPastDeadline),
+		Err:              err,
+	}
+}
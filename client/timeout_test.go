@@ -0,0 +1,85 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"terraform-provider-nsx-intervlan-routing/helpers"
+)
+
+func TestClientTimeoutNamesOperationAndPort(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false,
+		WithTimeout(20*time.Millisecond),
+		WithRetryPolicy(RetryPolicy{}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	err = c.PatchSegmentPort(context.Background(), helpers.PatchSegmentPortRequest{SegmentId: "segment-1", PortId: "port-1"})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a TimeoutError, got %v", err)
+	}
+	if timeoutErr.Operation != "PatchSegmentPort" || timeoutErr.SegmentID != "segment-1" || timeoutErr.PortID != "port-1" {
+		t.Errorf("unexpected timeout error: %+v", timeoutErr)
+	}
+	if timeoutErr.DeadlineExceeded {
+		t.Error("expected the request timeout not to be reported as the caller's deadline")
+	}
+}
+
+func TestClientHonoursContextDeadlineAcrossRetries(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	policy := DefaultRetryPolicy()
+	policy.MinDelay = time.Second
+	policy.MaxDelay = time.Second
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = c.GetSegmentPort(ctx, "segment-1", "port-1")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context deadline to end the retries, got %v", err)
+	}
+	if !timeoutErr.DeadlineExceeded {
+		t.Error("expected the timeout to be reported as the caller's deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the request to stop at the deadline, took %s", elapsed)
+	}
+}
+
+func TestClientErrorsWithoutDeadlineAreNotTimeouts(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	_, err = c.GetSegmentPort(context.Background(), "segment-1", "port-1")
+	if err == nil || errors.Is(err, ErrTimeout) {
+		t.Errorf("expected a non-timeout error, got %v", err)
+	}
+}
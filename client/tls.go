@@ -0,0 +1,126 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrThumbprintMismatch is returned when the NSX manager certificate does not
+// match the thumbprint given to WithCertificateThumbprint.
+var ErrThumbprintMismatch = errors.New("NSX manager certificate does not match the pinned thumbprint")
+
+// WithClientCertificate authenticates with an NSX principal identity using the
+// given PEM encoded certificate and private key. The client presents the
+// certificate on every connection and does not create a session.
+func WithClientCertificate(certPEM []byte, keyPEM []byte) NsxClientOption {
+	return func(c *Client) error {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		c.tlsConfig.Certificates = append(c.tlsConfig.Certificates, cert)
+		c.certificateAuth = true
+		return nil
+	}
+}
+
+// WithCACertificates verifies the NSX manager certificate against the given
+// PEM encoded CA bundle instead of the system trust store.
+func WithCACertificates(caPEM []byte) NsxClientOption {
+	return func(c *Client) error {
+		pool, err := ParseCACertificates(caPEM)
+		if err != nil {
+			return err
+		}
+		c.tlsConfig.RootCAs = pool
+		return nil
+	}
+}
+
+// ParseCACertificates returns a pool of the certificates in a PEM encoded CA
+// bundle, or an error if it holds none.
+func ParseCACertificates(caPEM []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no valid certificates found in CA bundle")
+	}
+	return pool, nil
+}
+
+// WithTLSServerName overrides the name used to verify the NSX manager
+// certificate, for when the manager is reached by IP address or an alias.
+func WithTLSServerName(serverName string) NsxClientOption {
+	return func(c *Client) error {
+		c.tlsConfig.ServerName = serverName
+		return nil
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version accepted from the NSX
+// manager, for example tls.VersionTLS12.
+func WithMinTLSVersion(version uint16) NsxClientOption {
+	return func(c *Client) error {
+		c.tlsConfig.MinVersion = version
+		return nil
+	}
+}
+
+// WithCertificateThumbprint pins the NSX manager certificate to the given
+// SHA-256 thumbprint, written as hex with or without colons. The pin replaces
+// CA verification, so it can be used with self-signed manager certificates.
+func WithCertificateThumbprint(thumbprint string) NsxClientOption {
+	return func(c *Client) error {
+		want, err := ParseCertificateThumbprint(thumbprint)
+		if err != nil {
+			return err
+		}
+
+		c.tlsConfig.InsecureSkipVerify = true
+		c.tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("NSX manager did not present a certificate")
+			}
+			got := sha256.Sum256(state.PeerCertificates[0].Raw)
+			if subtle.ConstantTimeCompare(got[:], want) != 1 {
+				return fmt.Errorf("%w: got %X", ErrThumbprintMismatch, got)
+			}
+			return nil
+		}
+		return nil
+	}
+}
+
+// ParseCertificateThumbprint decodes a SHA-256 certificate thumbprint given in
+// hex, with or without colons.
+func ParseCertificateThumbprint(thumbprint string) ([]byte, error) {
+	sum, err := hex.DecodeString(strings.ReplaceAll(thumbprint, ":", ""))
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("invalid SHA-256 thumbprint %q", thumbprint)
+	}
+	return sum, nil
+}
+
+// ParseTLSVersion converts a TLS version such as "1.2" to its crypto/tls
+// constant.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q, expected one of 1.0, 1.1, 1.2 or 1.3", version)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a self-signed PEM encoded certificate and key.
+func newTestCertificate(t *testing.T, commonName string) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestClientCertificateAuthentication(t *testing.T) {
+	certPEM, keyPEM := newTestCertificate(t, "terraform-pi")
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/session/create" {
+			t.Error("client certificate authentication must not create a session")
+		}
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "terraform-pi" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(context.Background(), server.URL, "", "", true, false, WithClientCertificate(certPEM, keyPEM))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+}
+
+func newTLSTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux, _ := newTestMux(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1"}`)
+	})
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientCustomCA(t *testing.T) {
+	server := newTLSTestServer(t)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	if _, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false); err == nil {
+		t.Fatal("expected verification against the system trust store to fail")
+	}
+
+	c, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false,
+		WithCACertificates(caPEM),
+		WithTLSServerName("example.com"),
+		WithMinTLSVersion(tls.VersionTLS12))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+}
+
+func TestClientCertificateThumbprint(t *testing.T) {
+	server := newTLSTestServer(t)
+	thumbprint := sha256.Sum256(server.Certificate().Raw)
+
+	if _, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithCertificateThumbprint(hex.EncodeToString(thumbprint[:]))); err != nil {
+		t.Fatalf("NewClient with matching thumbprint returned error: %s", err)
+	}
+
+	other := sha256.Sum256([]byte("another certificate"))
+	colonSeparated := strings.ReplaceAll(fmt.Sprintf("% X", other[:]), " ", ":")
+	_, err := NewClient(context.Background(), server.URL, "admin", "secret", false, false, WithCertificateThumbprint(colonSeparated))
+	if !errors.Is(err, ErrTLS) || !errors.Is(err, ErrThumbprintMismatch) {
+		t.Fatalf("expected a thumbprint mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// VCRMode selects whether a cassette is being recorded or replayed.
+type VCRMode string
+
+const (
+	// VCRModeRecord sends requests to NSX and saves every exchange to the
+	// cassette.
+	VCRModeRecord VCRMode = "record"
+	// VCRModeReplay answers requests from the cassette without contacting
+	// NSX.
+	VCRModeReplay VCRMode = "replay"
+)
+
+// ErrNoRecordedInteraction is returned in replay mode when the cassette has
+// no unused exchange matching a request.
+var ErrNoRecordedInteraction = errors.New("no recorded NSX interaction matches the request")
+
+// Cassette is the on-disk record of the exchanges with an NSX manager.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and the response NSX returned for it.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as written to a cassette. URI holds the path
+// and query only, so a cassette can be replayed against any host.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as written to a cassette.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// WithVCR records the client's exchanges with NSX to cassettePath, or replays
+// them from it, depending on mode. Cookies, XSRF tokens, Authorization headers
+// and login credentials are scrubbed before anything is written.
+func WithVCR(mode VCRMode, cassettePath string) NsxClientOption {
+	return func(c *Client) error {
+		if cassettePath == "" {
+			return fmt.Errorf("a cassette path is required for VCR mode %q", mode)
+		}
+		switch mode {
+		case VCRModeRecord, VCRModeReplay:
+		default:
+			return fmt.Errorf("unknown VCR mode %q, expected %q or %q", mode, VCRModeRecord, VCRModeReplay)
+		}
+		c.vcrMode = mode
+		c.vcrCassette = cassettePath
+		return nil
+	}
+}
+
+// newVCRDoer wraps next according to mode. In replay mode next is never
+// called.
+func newVCRDoer(mode VCRMode, cassettePath string, next HttpRequestDoer) (HttpRequestDoer, error) {
+	if mode == VCRModeRecord {
+		return &recordingDoer{next: next, path: cassettePath}, nil
+	}
+
+	data, err := os.ReadFile(cassettePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VCR cassette: %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse VCR cassette %s: %w", cassettePath, err)
+	}
+	return &replayingDoer{cassette: cassette, used: make([]bool, len(cassette.Interactions))}, nil
+}
+
+// recordingDoer forwards requests to next and appends each exchange to the
+// cassette. The file is rewritten after every exchange so that nothing is lost
+// if Terraform is interrupted.
+type recordingDoer struct {
+	next HttpRequestDoer
+	path string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func (d *recordingDoer) Do(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := d.next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URI:    req.URL.RequestURI(),
+			Header: redactHeader(req.Header),
+			Body:   redactBody(req.Header.Get("Content-Type"), reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+			Body:       string(respBody),
+		},
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cassette.Interactions = append(d.cassette.Interactions, interaction)
+	data, err := json.MarshalIndent(d.cassette, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(d.path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write VCR cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// replayingDoer answers each request with the first unused recorded
+// interaction having the same method and URI, so repeated calls to the same
+// endpoint are replayed in the order they were recorded.
+type replayingDoer struct {
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+func (d *replayingDoer) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+		_ = req.Body.Close()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	uri := req.URL.RequestURI()
+	for i, interaction := range d.cassette.Interactions {
+		if d.used[i] || interaction.Request.Method != req.Method || interaction.Request.URI != uri {
+			continue
+		}
+		d.used[i] = true
+
+		recorded := interaction.Response
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+			StatusCode:    recorded.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        recorded.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+			ContentLength: int64(len(recorded.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoRecordedInteraction, req.Method, uri)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVCRRecordsScrubbedCassetteAndReplaysIt(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "port-1", "display_name": "vm-1", "attachment": {"type": "CHILD"}}`)
+	})
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := NewClient(context.Background(), server.URL, "admin", "s3cret-password", false, false, WithVCR(VCRModeRecord, cassette))
+	if err != nil {
+		t.Fatalf("NewClient returned error while recording: %s", err)
+	}
+	if _, err := recorder.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error while recording: %s", err)
+	}
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("failed to read cassette: %s", err)
+	}
+	for _, secret := range []string{"s3cret-password", "session-1", "token-1"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains secret %q:\n%s", secret, data)
+		}
+	}
+
+	// Replay against a host that doesn't exist to prove NSX isn't contacted.
+	replayer, err := NewClient(context.Background(), "nsx.invalid", "admin", "other", false, false, WithVCR(VCRModeReplay, cassette))
+	if err != nil {
+		t.Fatalf("NewClient returned error while replaying: %s", err)
+	}
+	port, err := replayer.GetSegmentPort(context.Background(), "segment-1", "port-1")
+	if err != nil {
+		t.Fatalf("GetSegmentPort returned error while replaying: %s", err)
+	}
+	if port.DisplayName != "vm-1" {
+		t.Errorf("expected replayed port vm-1, got %q", port.DisplayName)
+	}
+
+	// Each recorded interaction is only replayed once.
+	_, err = replayer.GetSegmentPort(context.Background(), "segment-1", "port-1")
+	if !errors.Is(err, ErrNoRecordedInteraction) {
+		t.Errorf("expected ErrNoRecordedInteraction, got %v", err)
+	}
+}
+
+func TestVCRRejectsUnknownMode(t *testing.T) {
+	_, err := NewClient(context.Background(), "nsx.invalid", "admin", "secret", false, false, WithVCR("rewind", "cassette.json"))
+	if err == nil || !strings.Contains(err.Error(), "unknown VCR mode") {
+		t.Errorf("expected unknown VCR mode error, got %v", err)
+	}
+}
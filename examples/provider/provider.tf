@@ -25,9 +25,8 @@ resource "nsx-intervlan-routing_segment_port" "parent_example" {
   segment_port = {
     admin_state = "UP"
     attachment = {
-      id          = "9765bf41-9725-4714-977e-7f7395920de2"
-      traffic_tag = "1000"
-      type        = "PARENT"
+      id   = "9765bf41-9725-4714-977e-7f7395920de2"
+      type = "PARENT"
     }
     description   = "GCVE-PA-VM-ESX-2 Parent Port"
     display_name  = "GCVE-PA-VM-ESX-2.vmx@060af2c2-e9ff-4686-866c-c0daab1748d6"
@@ -0,0 +1,7 @@
+import {
+  to = nsx-intervlan-routing_segment_port.parent_example
+  identity = {
+    segment_id = "4d4c0f0a-6c50-420b-90f1-68fb7585cda4"
+    port_id    = "060af2c2-e9ff-4686-866c-c0daab1748d6"
+  }
+}
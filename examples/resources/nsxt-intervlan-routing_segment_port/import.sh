@@ -1,2 +1,5 @@
-terraform import nsxt-intervlan-routing_segment_port.parent_example "4d4c0f0a-6c5 0-420b-90f1-68fb7585cda4" "a274ac51-88f5-491f-a46f-840d409ce82f"
-terraform import nsxt-intervlan-routing_segment_port.child_example "2bfe8abf-4161-4788-9cbe-c444e9bf7454" "a274ac51-88f5-491f-a46f-840d409ce82f"
\ No newline at end of file
+# Segment ports are imported by segment ID and port ID
+terraform import nsx-intervlan-routing_segment_port.parent_example "4d4c0f0a-6c50-420b-90f1-68fb7585cda4/060af2c2-e9ff-4686-866c-c0daab1748d6"
+
+# or by the port's policy path
+terraform import nsx-intervlan-routing_segment_port.child_example "/infra/segments/2bfe8abf-4161-4788-9cbe-c444e9bf7454/ports/a274ac51-88f5-491f-a46f-840d409ce82f"
@@ -5,6 +5,15 @@ package helpers
 
 type ListSegmentPortsRequest struct {
 	SegmentId string `json:"segment_id"`
+	// Cursor is the opaque position returned by the previous page. Leave it
+	// empty to start from the first page.
+	Cursor string `json:"cursor,omitempty"`
+	// PageSize is the maximum number of ports returned per page. NSX uses its
+	// own default (1000) when it is zero.
+	PageSize       int      `json:"page_size,omitempty"`
+	SortBy         string   `json:"sort_by,omitempty"`
+	SortAscending  *bool    `json:"sort_ascending,omitempty"`
+	IncludedFields []string `json:"included_fields,omitempty"`
 }
 
 type ListSegmentPortsResponse struct {
@@ -12,6 +21,7 @@ type ListSegmentPortsResponse struct {
 	ResultCount   int              `json:"result_count"`
 	SortBy        string           `json:"sort_by"`
 	SortAscending bool             `json:"sort_ascending"`
+	Cursor        string           `json:"cursor,omitempty"`
 }
 
 type PatchSegmentPortRequest struct {
@@ -31,6 +41,9 @@ type ApiSegmentPort struct {
 	Path            string                  `json:"path,omitempty"`
 	RelativePath    string                  `json:"relative_path,omitempty"`
 	ResourceType    string                  `json:"resource_type,omitempty"`
+	// Revision is NSX's optimistic concurrency token. It is a pointer because
+	// zero is a valid revision which must still be sent.
+	Revision *int64 `json:"_revision,omitempty"`
 }
 
 type ApiPortAddressBinding struct {
@@ -44,6 +57,8 @@ type ApiPortAttachment struct {
 	AppId             string `json:"app_id,omitempty"`
 	ContextId         string `json:"context_id,omitempty"`
 	Id                string `json:"id,omitempty"`
-	TrafficTag        int32  `json:"traffic_tag,omitempty"`
-	Type              string `json:"type,omitempty"`
+	// TrafficTag is a pointer so that VLAN 0 is sent, while the tag is left
+	// out for PARENT ports, which have none.
+	TrafficTag *int32 `json:"traffic_tag,omitempty"`
+	Type       string `json:"type,omitempty"`
 }
@@ -19,9 +19,8 @@ func ConvertSegmentPortToTF(segment ApiSegmentPort) SegmentPort {
 		if address.MacAddress != "" {
 			pab.MacAddress = types.StringValue(address.MacAddress)
 		}
-		if address.VlanId > 0 {
-			pab.VlanId = types.Int32Value(address.VlanId)
-		}
+		// Required in the schema, so VLAN 0 is kept rather than read as unset.
+		pab.VlanId = types.Int32Value(address.VlanId)
 		addressBindings = append(addressBindings, pab)
 	}
 	segmentPort.AddressBindings = addressBindings
@@ -41,8 +40,8 @@ func ConvertSegmentPortToTF(segment ApiSegmentPort) SegmentPort {
 	if segment.Attachment.Id != "" {
 		attachment.Id = types.StringValue(segment.Attachment.Id)
 	}
-	if segment.Attachment.TrafficTag > 0 {
-		attachment.TrafficTag = types.Int32Value(segment.Attachment.TrafficTag)
+	if segment.Attachment.TrafficTag != nil {
+		attachment.TrafficTag = types.Int32Value(*segment.Attachment.TrafficTag)
 	}
 	if segment.Attachment.Type != "" {
 		attachment.Type = types.StringValue(segment.Attachment.Type)
@@ -75,6 +74,12 @@ func ConvertSegmentPortToTF(segment ApiSegmentPort) SegmentPort {
 	// Also not an optional field
 	segmentPort.ResourceType = types.StringValue(segment.ResourceType)
 
+	if segment.Revision != nil {
+		segmentPort.Revision = types.Int64Value(*segment.Revision)
+	} else {
+		segmentPort.Revision = types.Int64Null()
+	}
+
 	return segmentPort
 }
 
@@ -101,10 +106,14 @@ func ConvertTFToSegmentPort(segment SegmentPort) ApiSegmentPort {
 		AppId:             segment.Attachment.AppId.ValueString(),
 		ContextId:         segment.Attachment.ContextId.ValueString(),
 		Id:                segment.Attachment.Id.ValueString(),
-		TrafficTag:        segment.Attachment.TrafficTag.ValueInt32(),
 		Type:              segment.Attachment.Type.ValueString(),
 	}
 
+	if !segment.Attachment.TrafficTag.IsNull() && !segment.Attachment.TrafficTag.IsUnknown() {
+		trafficTag := segment.Attachment.TrafficTag.ValueInt32()
+		segmentPort.Attachment.TrafficTag = &trafficTag
+	}
+
 	segmentPort.Description = segment.Description.ValueString()
 	segmentPort.DisplayName = segment.DisplayName.ValueString()
 	segmentPort.Id = segment.Id.ValueString()
@@ -113,5 +122,10 @@ func ConvertTFToSegmentPort(segment SegmentPort) ApiSegmentPort {
 	segmentPort.RelativePath = segment.RelativePath.ValueString()
 	segmentPort.ResourceType = segment.ResourceType.ValueString()
 
+	if !segment.Revision.IsNull() && !segment.Revision.IsUnknown() {
+		revision := segment.Revision.ValueInt64()
+		segmentPort.Revision = &revision
+	}
+
 	return segmentPort
 }
@@ -0,0 +1,52 @@
+// Copyright (c) Technofish Consulting Pty Ltd
+// SPDX-License-Identifier: MPL-2.0
+
+package helpers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// TestTrafficTagRoundTrip sends a port to NSX as JSON and reads it back, as
+// Create and Read do, to check the traffic tag comes back as planned.
+func TestTrafficTagRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		trafficTag types.Int32
+		wantJSON   string
+	}{
+		{name: "VLAN 0", trafficTag: types.Int32Value(0), wantJSON: `"traffic_tag":0`},
+		{name: "VLAN 1001", trafficTag: types.Int32Value(1001), wantJSON: `"traffic_tag":1001`},
+		{name: "untagged", trafficTag: types.Int32Null()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := ConvertTFToSegmentPort(SegmentPort{
+				Attachment: PortAttachment{Type: types.StringValue("CHILD"), TrafficTag: tt.trafficTag},
+			})
+			body, err := json.Marshal(port)
+			if err != nil {
+				t.Fatalf("failed to marshal port: %s", err)
+			}
+			if tt.wantJSON != "" && !strings.Contains(string(body), tt.wantJSON) {
+				t.Errorf("expected the request body to contain %s, got %s", tt.wantJSON, body)
+			}
+			if tt.wantJSON == "" && strings.Contains(string(body), "traffic_tag") {
+				t.Errorf("expected the request body to leave out traffic_tag, got %s", body)
+			}
+
+			var read ApiSegmentPort
+			if err := json.Unmarshal(body, &read); err != nil {
+				t.Fatalf("failed to unmarshal port: %s", err)
+			}
+			if got := ConvertSegmentPortToTF(read).Attachment.TrafficTag; !got.Equal(tt.trafficTag) {
+				t.Errorf("traffic_tag read back as %s, want %s", got, tt.trafficTag)
+			}
+		})
+	}
+}
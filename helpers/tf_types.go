@@ -18,6 +18,7 @@ type SegmentPort struct {
 	Path            types.String         `tfsdk:"path"`
 	RelativePath    types.String         `tfsdk:"relative_path"`
 	ResourceType    types.String         `tfsdk:"resource_type"`
+	Revision        types.Int64          `tfsdk:"revision"`
 }
 
 type PortAddressBinding struct {
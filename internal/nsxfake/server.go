@@ -0,0 +1,485 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package nsxfake provides an in-memory stand-in for the parts of the NSX
+// Policy API used by the provider, so that the client and the acceptance tests
+// can run without an NSX manager.
+package nsxfake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+
+	"terraform-provider-nsx-intervlan-routing/helpers"
+)
+
+const (
+	// DefaultUsername and DefaultPassword are the credentials accepted by a
+	// server created by NewServer.
+	DefaultUsername = "admin"
+	DefaultPassword = "VMware1!VMware1!"
+
+	defaultPageSize = 1000
+	segmentsPath    = "/policy/api/v1/infra/segments/"
+)
+
+// Segment is the subset of an NSX segment kept by the fake server.
+type Segment struct {
+	Id           string `json:"id"`
+	DisplayName  string `json:"display_name,omitempty"`
+	Path         string `json:"path,omitempty"`
+	RelativePath string `json:"relative_path,omitempty"`
+	ParentPath   string `json:"parent_path,omitempty"`
+	ResourceType string `json:"resource_type,omitempty"`
+	Revision     int64  `json:"_revision"`
+}
+
+type segment struct {
+	Segment
+	ports map[string]helpers.ApiSegmentPort
+}
+
+// Server is an in-memory NSX manager serving the session, segment and segment
+// port APIs over HTTP.
+type Server struct {
+	*httptest.Server
+
+	username string
+	password string
+
+	mu       sync.Mutex
+	sessions map[string]string
+	segments map[string]*segment
+	requests []string
+}
+
+// NewServer starts a fake NSX manager which accepts DefaultUsername and
+// DefaultPassword. Call Close when done.
+func NewServer() *Server {
+	return NewServerWithCredentials(DefaultUsername, DefaultPassword)
+}
+
+// NewServerWithCredentials starts a fake NSX manager which accepts the given
+// credentials. Call Close when done.
+func NewServerWithCredentials(username string, password string) *Server {
+	s := &Server{
+		username: username,
+		password: password,
+		sessions: map[string]string{},
+		segments: map[string]*segment{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/session/create", s.createSession)
+	mux.HandleFunc("POST /api/session/destroy", s.authenticated(s.destroySession))
+	mux.HandleFunc("GET "+segmentsPath+"{segment}", s.authenticated(s.getSegment))
+	mux.HandleFunc("PUT "+segmentsPath+"{segment}", s.authenticated(s.putSegment))
+	mux.HandleFunc("PATCH "+segmentsPath+"{segment}", s.authenticated(s.putSegment))
+	mux.HandleFunc("DELETE "+segmentsPath+"{segment}", s.authenticated(s.deleteSegment))
+	mux.HandleFunc("GET "+segmentsPath+"{segment}/ports", s.authenticated(s.listPorts))
+	mux.HandleFunc("GET "+segmentsPath+"{segment}/ports/{port}", s.authenticated(s.getPort))
+	mux.HandleFunc("PUT "+segmentsPath+"{segment}/ports/{port}", s.authenticated(s.putPort))
+	mux.HandleFunc("PATCH "+segmentsPath+"{segment}/ports/{port}", s.authenticated(s.patchPort))
+	mux.HandleFunc("DELETE "+segmentsPath+"{segment}/ports/{port}", s.authenticated(s.deletePort))
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		s.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// AddSegment creates an empty segment.
+func (s *Server) AddSegment(segmentId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addSegment(Segment{Id: segmentId})
+}
+
+// SetSegmentPort stores a port on a segment, creating the segment if needed,
+// as if it had been created outside Terraform.
+func (s *Server) SetSegmentPort(segmentId string, port helpers.ApiSegmentPort) helpers.ApiSegmentPort {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.segments[segmentId]
+	if !ok {
+		seg = s.addSegment(Segment{Id: segmentId})
+	}
+	return seg.storePort(port.Id, port)
+}
+
+// SegmentPort returns a stored port.
+func (s *Server) SegmentPort(segmentId string, portId string) (helpers.ApiSegmentPort, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.segments[segmentId]
+	if !ok {
+		return helpers.ApiSegmentPort{}, false
+	}
+	port, ok := seg.ports[portId]
+	return port, ok
+}
+
+// ExpireSessions invalidates every session, as NSX does when they time out.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = map[string]string{}
+}
+
+// Sessions returns the number of sessions which have been created and not
+// yet destroyed or expired.
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Requests returns the method and path of every request received so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) addSegment(seg Segment) *segment {
+	seg.Path = "/infra/segments/" + seg.Id
+	seg.RelativePath = seg.Id
+	seg.ParentPath = "/infra"
+	seg.ResourceType = "Segment"
+	if seg.DisplayName == "" {
+		seg.DisplayName = seg.Id
+	}
+
+	stored := &segment{Segment: seg, ports: map[string]helpers.ApiSegmentPort{}}
+	s.segments[seg.Id] = stored
+	return stored
+}
+
+// storePort saves port under portId, filling in the fields NSX computes and
+// bumping the revision.
+func (seg *segment) storePort(portId string, port helpers.ApiSegmentPort) helpers.ApiSegmentPort {
+	revision := int64(0)
+	if existing, ok := seg.ports[portId]; ok && existing.Revision != nil {
+		revision = *existing.Revision + 1
+	}
+
+	port.Id = portId
+	port.Path = seg.Path + "/ports/" + portId
+	port.ParentPath = seg.Path
+	port.RelativePath = portId
+	port.ResourceType = "SegmentPort"
+	if port.DisplayName == "" {
+		port.DisplayName = portId
+	}
+	port.Revision = &revision
+
+	seg.ports[portId] = port
+	return port
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+	if r.PostForm.Get("j_username") != s.username || r.PostForm.Get("j_password") != s.password {
+		writeError(w, http.StatusForbidden, 403, "The credentials were incorrect or the account specified has been locked.")
+		return
+	}
+
+	session, token := randomToken(), randomToken()
+	s.mu.Lock()
+	s.sessions["JSESSIONID="+session+";"] = token
+	s.mu.Unlock()
+
+	w.Header().Set("Set-Cookie", "JSESSIONID="+session+"; Path=/; Secure; HttpOnly")
+	w.Header().Set("X-XSRF-TOKEN", token)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) destroySession(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	delete(s.sessions, r.Header.Get("Cookie"))
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticated rejects requests without a live session cookie and matching
+// XSRF token, the way NSX does.
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		token, ok := s.sessions[r.Header.Get("Cookie")]
+		s.mu.Unlock()
+
+		if !ok || token != r.Header.Get("X-XSRF-TOKEN") {
+			writeError(w, http.StatusForbidden, 403, "The credentials were incorrect or the account specified has been locked.")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) getSegment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.lookupSegment(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, seg.Segment)
+}
+
+func (s *Server) putSegment(w http.ResponseWriter, r *http.Request) {
+	var body Segment
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segmentId := r.PathValue("segment")
+	body.Id = segmentId
+	if existing, ok := s.segments[segmentId]; ok {
+		existing.DisplayName = body.DisplayName
+		existing.Revision++
+		writeJSON(w, http.StatusOK, existing.Segment)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.addSegment(body).Segment)
+}
+
+func (s *Server) deleteSegment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.lookupSegment(w, r)
+	if !ok {
+		return
+	}
+	if len(seg.ports) > 0 {
+		writeError(w, http.StatusBadRequest, 503040, fmt.Sprintf("Segment %s has %d ports attached and cannot be deleted.", seg.Id, len(seg.ports)))
+		return
+	}
+	delete(s.segments, seg.Id)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listPorts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.lookupSegment(w, r)
+	if !ok {
+		return
+	}
+
+	ids := make([]string, 0, len(seg.ports))
+	for id := range seg.ports {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	query := r.URL.Query()
+	start, pageSize := 0, defaultPageSize
+	if cursor := query.Get("cursor"); cursor != "" {
+		n, err := strconv.Atoi(cursor)
+		if err != nil || n < 0 || n > len(ids) {
+			writeError(w, http.StatusBadRequest, 255, "Invalid cursor "+cursor)
+			return
+		}
+		start = n
+	}
+	if size := query.Get("page_size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, 255, "Invalid page_size "+size)
+			return
+		}
+		pageSize = n
+	}
+
+	end := min(start+pageSize, len(ids))
+	response := helpers.ListSegmentPortsResponse{
+		Results:       []helpers.ApiSegmentPort{},
+		ResultCount:   len(ids),
+		SortBy:        "id",
+		SortAscending: true,
+	}
+	for _, id := range ids[start:end] {
+		response.Results = append(response.Results, seg.ports[id])
+	}
+	if end < len(ids) {
+		response.Cursor = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) getPort(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	port, ok := s.lookupPort(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, port)
+}
+
+func (s *Server) putPort(w http.ResponseWriter, r *http.Request) {
+	var body helpers.ApiSegmentPort
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.lookupSegment(w, r)
+	if !ok {
+		return
+	}
+	portId := r.PathValue("port")
+	if !checkRevision(w, seg.ports[portId], body.Revision) {
+		return
+	}
+	writeJSON(w, http.StatusOK, seg.storePort(portId, body))
+}
+
+// patchPort merges the fields present in the request body into the stored
+// port. Like NSX, it creates the port if it doesn't exist and returns an
+// empty body.
+func (s *Server) patchPort(w http.ResponseWriter, r *http.Request) {
+	var patch map[string]json.RawMessage
+	if !decodeBody(w, r, &patch) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.lookupSegment(w, r)
+	if !ok {
+		return
+	}
+	portId := r.PathValue("port")
+	existing := seg.ports[portId]
+
+	var revision *int64
+	if raw, ok := patch["_revision"]; ok {
+		revision = new(int64)
+		if err := json.Unmarshal(raw, revision); err != nil {
+			writeError(w, http.StatusBadRequest, 255, "Invalid _revision")
+			return
+		}
+	}
+	if !checkRevision(w, existing, revision) {
+		return
+	}
+
+	merged := map[string]json.RawMessage{}
+	current, _ := json.Marshal(existing)
+	_ = json.Unmarshal(current, &merged)
+	for k, v := range patch {
+		merged[k] = v
+	}
+
+	var port helpers.ApiSegmentPort
+	raw, _ := json.Marshal(merged)
+	if err := json.Unmarshal(raw, &port); err != nil {
+		writeError(w, http.StatusBadRequest, 255, err.Error())
+		return
+	}
+	port.Revision = existing.Revision
+	seg.storePort(portId, port)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deletePort(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.lookupSegment(w, r)
+	if !ok {
+		return
+	}
+	delete(seg.ports, r.PathValue("port"))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) lookupSegment(w http.ResponseWriter, r *http.Request) (*segment, bool) {
+	segmentId := r.PathValue("segment")
+	seg, ok := s.segments[segmentId]
+	if !ok {
+		writeError(w, http.StatusNotFound, 500090, fmt.Sprintf("The path=[/infra/segments/%s] is invalid", segmentId))
+	}
+	return seg, ok
+}
+
+func (s *Server) lookupPort(w http.ResponseWriter, r *http.Request) (helpers.ApiSegmentPort, bool) {
+	seg, ok := s.lookupSegment(w, r)
+	if !ok {
+		return helpers.ApiSegmentPort{}, false
+	}
+	portId := r.PathValue("port")
+	port, ok := seg.ports[portId]
+	if !ok {
+		writeError(w, http.StatusNotFound, 500090, fmt.Sprintf("The path=[%s/ports/%s] is invalid", seg.Path, portId))
+	}
+	return port, ok
+}
+
+// checkRevision rejects a write whose _revision doesn't match the stored
+// port. Writes without a _revision are accepted.
+func checkRevision(w http.ResponseWriter, existing helpers.ApiSegmentPort, revision *int64) bool {
+	if revision == nil || existing.Revision == nil || *revision == *existing.Revision {
+		return true
+	}
+	writeError(w, http.StatusPreconditionFailed, 604,
+		fmt.Sprintf("The object AbstractPolicyResource [%s] was modified by somebody else. Please refresh and try again.", existing.Path))
+	return false
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, 255, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the format returned by the NSX API.
+func writeError(w http.ResponseWriter, status int, code int, message string) {
+	writeJSON(w, status, map[string]any{
+		"httpStatus":    http.StatusText(status),
+		"error_code":    code,
+		"module_name":   "nsxfake",
+		"error_message": message,
+	})
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
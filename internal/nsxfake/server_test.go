@@ -0,0 +1,149 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package nsxfake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"terraform-provider-nsx-intervlan-routing/client"
+	"terraform-provider-nsx-intervlan-routing/helpers"
+)
+
+func newTestClient(t *testing.T) (*Server, *client.Client) {
+	t.Helper()
+
+	server := NewServer()
+	t.Cleanup(server.Close)
+
+	c, err := client.NewClient(context.Background(), server.URL, DefaultUsername, DefaultPassword, false, false)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+	return server, c
+}
+
+func TestServerRejectsBadCredentials(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	_, err := client.NewClient(context.Background(), server.URL, DefaultUsername, "wrong", false, false)
+	if !errors.Is(err, client.ErrAuthentication) {
+		t.Fatalf("expected ErrAuthentication, got %v", err)
+	}
+}
+
+func TestServerSegmentPortLifecycle(t *testing.T) {
+	server, c := newTestClient(t)
+	server.AddSegment("segment-1")
+	ctx := context.Background()
+
+	trafficTag := int32(1001)
+	attachment := helpers.ApiPortAttachment{Type: "CHILD", ContextId: "parent-vif", TrafficTag: &trafficTag}
+	created, err := c.PutSegmentPort(ctx, helpers.PatchSegmentPortRequest{
+		SegmentId:      "segment-1",
+		PortId:         "child-1",
+		ApiSegmentPort: helpers.ApiSegmentPort{AdminState: "UP", Attachment: attachment},
+	})
+	if err != nil {
+		t.Fatalf("PutSegmentPort returned error: %s", err)
+	}
+	if created.Path != "/infra/segments/segment-1/ports/child-1" || *created.Revision != 0 {
+		t.Errorf("unexpected created port: path %q revision %d", created.Path, *created.Revision)
+	}
+
+	update := helpers.PatchSegmentPortRequest{
+		SegmentId:      "segment-1",
+		PortId:         "child-1",
+		ApiSegmentPort: helpers.ApiSegmentPort{Description: "updated", Attachment: attachment, Revision: created.Revision},
+	}
+	if err := c.PatchSegmentPort(ctx, update); err != nil {
+		t.Fatalf("PatchSegmentPort returned error: %s", err)
+	}
+
+	port, err := c.GetSegmentPort(ctx, "segment-1", "child-1")
+	if err != nil {
+		t.Fatalf("GetSegmentPort returned error: %s", err)
+	}
+	if port.Description != "updated" || port.AdminState != "UP" || *port.Revision != 1 {
+		t.Errorf("unexpected patched port: %+v", port)
+	}
+
+	// The revision sent with the first update is now stale.
+	if err := c.PatchSegmentPort(ctx, update); !client.IsPreconditionFailed(err) {
+		t.Errorf("expected a precondition failure for a stale revision, got %v", err)
+	}
+
+	if err := c.DeleteSegmentPort(ctx, "segment-1", "child-1"); err != nil {
+		t.Fatalf("DeleteSegmentPort returned error: %s", err)
+	}
+	if _, err := c.GetSegmentPort(ctx, "segment-1", "child-1"); !client.IsNotFound(err) {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestServerListSegmentPortsPaginates(t *testing.T) {
+	server, c := newTestClient(t)
+	for i := range 5 {
+		server.SetSegmentPort("segment-1", helpers.ApiSegmentPort{Id: fmt.Sprintf("port-%d", i)})
+	}
+
+	page, err := c.ListSegmentPortsPage(context.Background(), helpers.ListSegmentPortsRequest{SegmentId: "segment-1", PageSize: 2})
+	if err != nil {
+		t.Fatalf("ListSegmentPortsPage returned error: %s", err)
+	}
+	if len(page.Results) != 2 || page.Cursor == "" || page.ResultCount != 5 {
+		t.Errorf("unexpected first page: %+v", page)
+	}
+
+	all, err := c.ListSegmentPorts(context.Background(), helpers.ListSegmentPortsRequest{SegmentId: "segment-1", PageSize: 2})
+	if err != nil {
+		t.Fatalf("ListSegmentPorts returned error: %s", err)
+	}
+	if len(all.Results) != 5 {
+		t.Errorf("expected 5 ports across all pages, got %d", len(all.Results))
+	}
+}
+
+func TestServerExpiredSessionIsRenewed(t *testing.T) {
+	server, c := newTestClient(t)
+	server.SetSegmentPort("segment-1", helpers.ApiSegmentPort{Id: "port-1"})
+	server.ExpireSessions()
+
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error after session expiry: %s", err)
+	}
+}
+
+func TestServerClientCloseDestroysSession(t *testing.T) {
+	server, c := newTestClient(t)
+	server.SetSegmentPort("segment-1", helpers.ApiSegmentPort{Id: "port-1"})
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close returned error: %s", err)
+	}
+	if n := server.Sessions(); n != 0 {
+		t.Fatalf("expected the session to be destroyed, %d left", n)
+	}
+
+	// The client logs in again when it is used after Close.
+	if _, err := c.GetSegmentPort(context.Background(), "segment-1", "port-1"); err != nil {
+		t.Fatalf("GetSegmentPort returned error after Close: %s", err)
+	}
+	if n := server.Sessions(); n != 1 {
+		t.Errorf("expected a new session, got %d", n)
+	}
+}
+
+func TestServerUnknownSegment(t *testing.T) {
+	_, c := newTestClient(t)
+
+	_, err := c.GetSegmentPort(context.Background(), "missing", "port-1")
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 404 || apiErr.ErrorCode != 500090 {
+		t.Errorf("expected an NSX not found error, got %v", err)
+	}
+}
@@ -5,7 +5,6 @@ package provider
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -28,7 +27,7 @@ func NewSegmentPortDataSource() datasource.DataSource {
 }
 
 type SegmentPortDataSource struct {
-	client client.Client
+	client client.NsxClientInterface
 }
 
 type SegmentPortDataSourceModel struct {
@@ -45,7 +44,7 @@ func (d *SegmentPortDataSource) Configure(_ context.Context, req datasource.Conf
 	}
 
 	p, ok := req.ProviderData.(*NsxIntervlanRoutingProviderData)
-	if !ok || p.Client.Session == "" {
+	if !ok || p.Client == nil {
 		resp.Diagnostics.AddError(
 			"Invalid Provider Data",
 			fmt.Sprintf("Expected *NsxIntervlanRoutingProviderData with initialized client, got: %T", req.ProviderData),
@@ -183,6 +182,11 @@ func (d *SegmentPortDataSource) Schema(_ context.Context, _ datasource.SchemaReq
 						MarkdownDescription: "Resource type of segment port. Can only be set to 'SegmentPort'",
 						Computed:            true,
 					},
+					"revision": schema.Int64Attribute{
+						Description:         "NSX revision of the segment port. Incremented by NSX on every change.",
+						MarkdownDescription: "NSX revision of the segment port. Incremented by NSX on every change.",
+						Computed:            true,
+					},
 				},
 			},
 		},
@@ -192,6 +196,9 @@ func (d *SegmentPortDataSource) Schema(_ context.Context, _ datasource.SchemaReq
 // Read refreshes the Terraform state with the latest data.
 func (d *SegmentPortDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
 	tflog.Debug(ctx, "Preparing to read item data source")
+	ctx, cancel := context.WithTimeout(ctx, segmentPortReadTimeout)
+	defer cancel()
+
 	var state SegmentPortDataSourceModel
 
 	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
@@ -199,38 +206,18 @@ func (d *SegmentPortDataSource) Read(ctx context.Context, req datasource.ReadReq
 		return
 	}
 
-	portsResponse, err := d.client.ListSegmentPorts(ctx, state.SegmentId.ValueString())
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to Read segment ports for ",
-			err.Error(),
-		)
-		return
-	}
-
-	var segmentPorts helpers.ListSegmentPortsResponse
-	if portsResponse.StatusCode != 200 {
-		resp.Diagnostics.AddError(
-			"Unexpected HTTP error code received for Item",
-			portsResponse.Status,
-		)
-		return
-	}
-
-	// Map the response object to the ListSegmentPortsResponse struct
-	if err := json.NewDecoder(portsResponse.Body).Decode(&segmentPorts); err != nil {
-		resp.Diagnostics.AddError(
-			"Invalid format received for segment ports",
-			err.Error(),
-		)
-		return
-	}
-
 	//var addressBindings []helpers.PortAddressBinding
 	lowerVmName := strings.ToLower(state.VmName.ValueString())
-	tflog.Debug(ctx, "Received segment port results: ", map[string]any{"segment_port": segmentPorts.Results})
 
-	for _, segment := range segmentPorts.Results {
+	// Ports are streamed page by page, so large trunk segments are only read
+	// as far as the matching port.
+	params := helpers.ListSegmentPortsRequest{SegmentId: state.SegmentId.ValueString()}
+	for segment, err := range d.client.IterateSegmentPorts(ctx, params) {
+		if err != nil {
+			addClientError(&resp.Diagnostics, "Unable to Read segment ports for "+state.SegmentId.ValueString(), err)
+			return
+		}
+
 		lowerDisplayName := strings.ToLower(segment.DisplayName)
 
 		if strings.HasPrefix(lowerDisplayName, lowerVmName) {
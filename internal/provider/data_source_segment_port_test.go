@@ -6,6 +6,8 @@ package provider
 import (
 	"testing"
 
+	"terraform-provider-nsx-intervlan-routing/helpers"
+
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
 	"github.com/hashicorp/terraform-plugin-testing/statecheck"
@@ -13,24 +15,50 @@ import (
 )
 
 func TestAccSegmentPortDataSource(t *testing.T) {
+	server := testAccNsxFake(t)
+	server.SetSegmentPort("4d4c0f0a-6c50-420b-90f1-68fb7585cda4", helpers.ApiSegmentPort{
+		Id:          "060af2c2-e9ff-4686-866c-c0daab1748d6",
+		DisplayName: "test_fw_name.vmx@060af2c2-e9ff-4686-866c-c0daab1748d6",
+		AdminState:  "UP",
+		Attachment: helpers.ApiPortAttachment{
+			Id:   "9765bf41-9725-4714-977e-7f7395920de2",
+			Type: "PARENT",
+		},
+	})
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Read testing
 			{
-				Config: testAccSegmentPortDataSourceConfig,
+				Config: testAccProviderConfig(server) + testAccSegmentPortDataSourceConfig,
 				ConfigStateChecks: []statecheck.StateCheck{
 					statecheck.ExpectKnownValue(
 						"data.nsx-intervlan-routing_segment_port.example",
 						tfjsonpath.New("segment_id"),
-						knownvalue.StringExact("4d4c0f0a-6c5 0-420b-90f1-68fb7585cda4"),
+						knownvalue.StringExact("4d4c0f0a-6c50-420b-90f1-68fb7585cda4"),
 					),
 					statecheck.ExpectKnownValue(
 						"data.nsx-intervlan-routing_segment_port.example",
 						tfjsonpath.New("vm_name"),
 						knownvalue.StringExact("test_fw_name"),
 					),
+					statecheck.ExpectKnownValue(
+						"data.nsx-intervlan-routing_segment_port.example",
+						tfjsonpath.New("segment_port").AtMapKey("id"),
+						knownvalue.StringExact("060af2c2-e9ff-4686-866c-c0daab1748d6"),
+					),
+					statecheck.ExpectKnownValue(
+						"data.nsx-intervlan-routing_segment_port.example",
+						tfjsonpath.New("segment_port").AtMapKey("attachment").AtMapKey("id"),
+						knownvalue.StringExact("9765bf41-9725-4714-977e-7f7395920de2"),
+					),
+					statecheck.ExpectKnownValue(
+						"data.nsx-intervlan-routing_segment_port.example",
+						tfjsonpath.New("segment_port").AtMapKey("path"),
+						knownvalue.StringExact("/infra/segments/4d4c0f0a-6c50-420b-90f1-68fb7585cda4/ports/060af2c2-e9ff-4686-866c-c0daab1748d6"),
+					),
 				},
 			},
 		},
@@ -39,33 +67,7 @@ func TestAccSegmentPortDataSource(t *testing.T) {
 
 const testAccSegmentPortDataSourceConfig = `
 data "nsx-intervlan-routing_segment_port" "example" {
-  segment_id    = "4d4c0f0a-6c5 0-420b-90f1-68fb7585cda4"
-  vm_name		= "test_fw_name"
+  segment_id = "4d4c0f0a-6c50-420b-90f1-68fb7585cda4"
+  vm_name    = "test_fw_name"
 }
 `
-
-//const testAccExampleDataSourceResult = `
-//{
-//  "results": [
-//    {
-//      "resource_type": "SegmentPort",
-//	  "id": "a274ac51-88f5-491f-a46f-840d409ce82f",
-//	  "display_name": "a274ac51-88f5-491f-a46f-840d409ce82f",
-//	  "path": "/infra/segments/production-t1-seg/ports/a274ac51-88f5-491f-a46f-840d409ce82f",
-//	  "relative_path": "a274ac51-88f5-491f-a46f-840d409ce82f",
-//	  "parent_path": "/infra/segments/production-t1-seg",
-//	  "marked_for_delete": false,
-//	  "_create_user": "system",
-//	  "_create_time": 1544503100539,
-//	  "_last_modified_user": "system",
-//	  "_last_modified_time": 1544503100539,
-//	  "_system_owned": true,
-//	  "_protection": "NOT_PROTECTED",
-//	  "_revision": 0
-//    }
-//  ],
-//  "result_count": 1,
-//  "sort_by": "display_name",
-//  "sort_ascending": true,
-//}
-//`
@@ -0,0 +1,117 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"terraform-provider-nsx-intervlan-routing/client"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// addClientError adds an error diagnostic for an error returned by the NSX
+// client. NSX API errors are expanded so that the message and any related
+// errors returned by NSX are shown to the user.
+func addClientError(diags *diag.Diagnostics, summary string, err error) {
+	var timeoutErr *client.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		diags.AddError(summary, formatTimeoutError(timeoutErr))
+		return
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		diags.AddError(summary, err.Error())
+		return
+	}
+	diags.AddError(summary, formatAPIError(apiErr))
+}
+
+func formatAPIError(apiErr *client.APIError) string {
+	var b strings.Builder
+	if apiErr.ErrorMessage != "" {
+		b.WriteString(apiErr.ErrorMessage)
+		b.WriteString("\n\n")
+	}
+
+	fmt.Fprintf(&b, "HTTP status: %d", apiErr.StatusCode)
+	if apiErr.HTTPStatus != "" {
+		fmt.Fprintf(&b, " %s", apiErr.HTTPStatus)
+	}
+	if apiErr.ErrorCode != 0 {
+		fmt.Fprintf(&b, "\nNSX error code: %d", apiErr.ErrorCode)
+	}
+	if apiErr.ModuleName != "" {
+		fmt.Fprintf(&b, "\nNSX module: %s", apiErr.ModuleName)
+	}
+
+	if len(apiErr.RelatedErrors) > 0 {
+		b.WriteString("\n\nRelated errors:")
+		for _, related := range apiErr.RelatedErrors {
+			b.WriteString("\n  - ")
+			if related.ErrorCode != 0 {
+				fmt.Fprintf(&b, "[%d] ", related.ErrorCode)
+			}
+			if related.ModuleName != "" {
+				fmt.Fprintf(&b, "%s: ", related.ModuleName)
+			}
+			b.WriteString(related.ErrorMessage)
+		}
+	}
+
+	return b.String()
+}
+
+func formatTimeoutError(timeoutErr *client.TimeoutError) string {
+	target := fmt.Sprintf("segment %q", timeoutErr.SegmentID)
+	if timeoutErr.PortID != "" {
+		target = fmt.Sprintf("port %q on segment %q", timeoutErr.PortID, timeoutErr.SegmentID)
+	}
+	hint := "increase request_timeout in the provider configuration"
+	if timeoutErr.DeadlineExceeded {
+		hint = "increase the resource's timeouts"
+	}
+	return fmt.Sprintf("The %s call for %s did not complete in time. "+
+		"Check that the NSX manager is healthy, or %s if the manager is slow to respond.\n\nError: %s",
+		timeoutErr.Operation, target, hint, timeoutErr.Err)
+}
+
+// addConfigureError adds an error diagnostic on the host attribute for a
+// failure to create the NSX client, with a summary describing which step
+// failed.
+func addConfigureError(diags *diag.Diagnostics, host string, err error) {
+	var summary, hint string
+	switch {
+	case errors.Is(err, client.ErrInvalidHost):
+		summary = "Invalid NSX Manager Host"
+		hint = "Set host to a hostname, IP address or https:// URL of the NSX manager."
+	case errors.Is(err, client.ErrTLS):
+		summary = "Unable to Establish a Trusted Connection to the NSX Manager"
+		hint = "Check that the manager certificate is signed by a CA in ca_file or ca_pem, " +
+			"that tls_server_name and manager_thumbprint are correct, or set insecure = true for testing."
+	case errors.Is(err, client.ErrUnreachable):
+		summary = "Unable to Reach the NSX Manager"
+		hint = "Check that host is correct and that the NSX manager is reachable from where Terraform runs."
+	case errors.Is(err, client.ErrAuthentication):
+		summary = "NSX Manager Rejected the Credentials"
+		hint = "Check the username and password, or the principal identity certificate, configured for the provider."
+	case errors.Is(err, client.ErrMissingXsrfToken):
+		summary = "NSX Manager Did Not Return a Session Token"
+		hint = "The session was created but no X-XSRF-TOKEN header was returned. Check that host points at an NSX manager " +
+			"and not at a proxy which strips response headers."
+	default:
+		summary = "Unable to Create the NSX API Client"
+		hint = "Check the provider configuration."
+	}
+
+	diags.AddAttributeError(
+		path.Root("host"),
+		summary,
+		fmt.Sprintf("The provider could not connect to the NSX manager at %q. %s\n\nError: %s", host, hint, err),
+	)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-provider-nsx-intervlan-routing/client"
+	"terraform-provider-nsx-intervlan-routing/helpers"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestAddClientErrorTimeout(t *testing.T) {
+	var diags diag.Diagnostics
+	addClientError(&diags, "Unable to Read Segment Port", &client.TimeoutError{
+		Operation:        "GetSegmentPort",
+		SegmentID:        "segment-1",
+		PortID:           "port-1",
+		DeadlineExceeded: true,
+		Err:              context.DeadlineExceeded,
+	})
+
+	if len(diags) != 1 {
+		t.Fatalf("expected one diagnostic, got %v", diags)
+	}
+	detail := diags[0].Detail()
+	for _, want := range []string{"GetSegmentPort", `port "port-1" on segment "segment-1"`, "timeouts"} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("expected the diagnostic to mention %q, got %q", want, detail)
+		}
+	}
+}
+
+func TestAddClientErrorRequestTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/session/create", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "JSESSIONID=session-1; Path=/; HttpOnly")
+		w.Header().Set("X-XSRF-TOKEN", "token-1")
+	})
+	mux.HandleFunc("/policy/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c, err := client.NewClient(context.Background(), server.URL, "admin", "secret", false, false,
+		client.WithTimeout(20*time.Millisecond),
+		client.WithRetryPolicy(client.RetryPolicy{}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %s", err)
+	}
+
+	// The operation has plenty of time left, so only the client's own
+	// request timeout can have expired.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err = c.ListSegmentPortsPage(ctx, helpers.ListSegmentPortsRequest{SegmentId: "segment-1"})
+	if !errors.Is(err, client.ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	var diags diag.Diagnostics
+	addClientError(&diags, "Unable to List Segment Ports", err)
+	detail := diags[0].Detail()
+	if !strings.Contains(detail, `segment "segment-1"`) || !strings.Contains(detail, "request_timeout") {
+		t.Errorf("expected the diagnostic to name the segment and request_timeout, got %q", detail)
+	}
+	if strings.Contains(detail, "resource's timeouts") {
+		t.Errorf("expected the diagnostic not to blame the resource's timeouts, got %q", detail)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// requiresReplace plans a replacement whenever the attribute changes. reason
+// explains why, and is shown with the plan modifier's description.
+func requiresReplace(reason string) planmodifier.String {
+	return stringplanmodifier.RequiresReplaceIf(
+		func(_ context.Context, _ planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+			resp.RequiresReplace = true
+		},
+		reason,
+		reason,
+	)
+}
+
+// attachmentTypeChange plans a replacement when the attachment type of a port
+// changes, since NSX can't convert a port in place. A PARENT port can't become
+// a CHILD port at all: it is the VM's own port, which has to stay attached to
+// its VIF, so the change is rejected unless segment_id or port_id change too
+// and the CHILD port replaces it with a different port.
+func attachmentTypeChange() planmodifier.String {
+	return attachmentTypeChangeModifier{}
+}
+
+type attachmentTypeChangeModifier struct{}
+
+func (m attachmentTypeChangeModifier) Description(_ context.Context) string {
+	return "Changing the attachment type replaces the port. A PARENT port can't be changed to a CHILD port unless segment_id or port_id change too."
+}
+
+func (m attachmentTypeChangeModifier) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m attachmentTypeChangeModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	// Nothing to compare when the port is being created or destroyed, or the
+	// new type isn't known yet.
+	if req.StateValue.IsNull() || req.PlanValue.IsNull() || req.PlanValue.IsUnknown() {
+		return
+	}
+	if req.StateValue.Equal(req.PlanValue) {
+		return
+	}
+
+	if req.StateValue.ValueString() == "PARENT" && req.PlanValue.ValueString() == "CHILD" {
+		samePort, diags := isSameSegmentPort(ctx, req)
+		resp.Diagnostics.Append(diags...)
+		if diags.HasError() {
+			return
+		}
+		if !samePort {
+			resp.RequiresReplace = true
+			return
+		}
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Cannot Change a PARENT Port to a CHILD Port",
+			"This port is the VM's PARENT port, which stays attached to the VM's VIF, so it can't be replaced by a CHILD port. "+
+				"Keep this resource as the PARENT port and add a separate segment_port resource for the CHILD port, "+
+				"with its own port_id and context_id set to this port's attachment id.",
+		)
+		return
+	}
+	resp.RequiresReplace = true
+}
+
+// isSameSegmentPort reports whether the plan keeps the segment_id and port_id
+// in state. IDs which aren't known yet count as changed.
+func isSameSegmentPort(ctx context.Context, req planmodifier.StringRequest) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	for _, name := range []string{"segment_id", "port_id"} {
+		var stateValue, planValue types.String
+		diags.Append(req.State.GetAttribute(ctx, path.Root(name), &stateValue)...)
+		diags.Append(req.Plan.GetAttribute(ctx, path.Root(name), &planValue)...)
+		if diags.HasError() {
+			return false, diags
+		}
+		if !stateValue.Equal(planValue) {
+			return false, diags
+		}
+	}
+	return true, diags
+}
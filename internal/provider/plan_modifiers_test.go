@@ -0,0 +1,76 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"terraform-provider-nsx-intervlan-routing/helpers"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestAttachmentTypeChange(t *testing.T) {
+	tests := []struct {
+		name        string
+		state, plan types.String
+		// planPortId defaults to the port_id in state.
+		planPortId  types.String
+		wantReplace bool
+		wantError   bool
+	}{
+		{name: "create", state: types.StringNull(), plan: types.StringValue("CHILD")},
+		{name: "unchanged", state: types.StringValue("PARENT"), plan: types.StringValue("PARENT")},
+		{name: "unknown", state: types.StringValue("CHILD"), plan: types.StringUnknown()},
+		{name: "child to parent", state: types.StringValue("CHILD"), plan: types.StringValue("PARENT"), wantReplace: true},
+		{name: "parent to child", state: types.StringValue("PARENT"), plan: types.StringValue("CHILD"), wantError: true},
+		{
+			name:        "parent to child on another port",
+			state:       types.StringValue("PARENT"),
+			plan:        types.StringValue("CHILD"),
+			planPortId:  types.StringValue("port-2"),
+			wantReplace: true,
+		},
+		{
+			name:        "parent to child with unknown port",
+			state:       types.StringValue("PARENT"),
+			plan:        types.StringValue("CHILD"),
+			planPortId:  types.StringUnknown(),
+			wantReplace: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateModel := testSegmentPortModel(helpers.PortAttachment{Type: tt.state}, nil)
+			planModel := testSegmentPortModel(helpers.PortAttachment{Type: tt.plan}, nil)
+			if !tt.planPortId.IsNull() {
+				planModel.PortId = tt.planPortId
+			}
+			state := testSegmentPortConfig(t, stateModel)
+			plan := testSegmentPortConfig(t, planModel)
+
+			req := planmodifier.StringRequest{
+				Path:       path.Root("segment_port").AtName("attachment").AtName("type"),
+				State:      tfsdk.State{Schema: state.Schema, Raw: state.Raw},
+				Plan:       tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw},
+				StateValue: tt.state,
+				PlanValue:  tt.plan,
+			}
+			resp := planmodifier.StringResponse{PlanValue: tt.plan}
+			attachmentTypeChange().PlanModifyString(context.Background(), req, &resp)
+
+			if resp.RequiresReplace != tt.wantReplace {
+				t.Errorf("RequiresReplace = %v, want %v", resp.RequiresReplace, tt.wantReplace)
+			}
+			if resp.Diagnostics.HasError() != tt.wantError {
+				t.Errorf("errors = %v, want error %v", resp.Diagnostics, tt.wantError)
+			}
+		})
+	}
+}
@@ -5,9 +5,20 @@ package provider
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
 	"terraform-provider-nsx-intervlan-routing/client"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
@@ -24,24 +35,60 @@ type NsxIntervlanRoutingProvider struct {
 	// provider is built and ran locally, and "test" when running acceptance
 	// testing.
 	version string
+
+	// clients holds every client created by Configure, so that their
+	// sessions can be closed when the provider stops.
+	clientsMu sync.Mutex
+	clients   []*client.Client
 }
 
 type NsxIntervlanRoutingProviderData struct {
-	Client   client.Client
+	// Client is shared by every resource and data source so that a session
+	// refreshed by one of them is picked up by all of them.
+	Client   *client.Client
 	Host     string
 	Username string
 	Password string
 	Insecure bool
 	Debug    bool
+
+	RetryOnRevisionConflict bool
 }
 
 // NsxIntervlanRoutingProviderModel describes the provider data model.
 type NsxIntervlanRoutingProviderModel struct {
 	Host     types.String `tfsdk:"host"`
+	Hosts    types.List   `tfsdk:"hosts"`
 	Username types.String `tfsdk:"username"`
 	Password types.String `tfsdk:"password"`
 	Insecure types.Bool   `tfsdk:"insecure"`
 	Debug    types.Bool   `tfsdk:"debug"`
+
+	MaxRetries         types.Int64 `tfsdk:"max_retries"`
+	RetryMinDelay      types.Int64 `tfsdk:"retry_min_delay"`
+	RetryMaxDelay      types.Int64 `tfsdk:"retry_max_delay"`
+	RetryOnStatusCodes types.List  `tfsdk:"retry_on_status_codes"`
+	RequestTimeout     types.Int64 `tfsdk:"request_timeout"`
+
+	MaxRequestsPerSecond  types.Int64 `tfsdk:"max_requests_per_second"`
+	MaxConcurrentRequests types.Int64 `tfsdk:"max_concurrent_requests"`
+	SessionIdleTimeout    types.Int64 `tfsdk:"session_idle_timeout"`
+
+	RetryOnRevisionConflict types.Bool `tfsdk:"retry_on_revision_conflict"`
+
+	ClientAuthCertFile types.String `tfsdk:"client_auth_cert_file"`
+	ClientAuthKeyFile  types.String `tfsdk:"client_auth_key_file"`
+	ClientAuthCert     types.String `tfsdk:"client_auth_cert"`
+	ClientAuthKey      types.String `tfsdk:"client_auth_key"`
+
+	CaFile            types.String `tfsdk:"ca_file"`
+	CaPem             types.String `tfsdk:"ca_pem"`
+	TlsServerName     types.String `tfsdk:"tls_server_name"`
+	MinTlsVersion     types.String `tfsdk:"min_tls_version"`
+	ManagerThumbprint types.String `tfsdk:"manager_thumbprint"`
+
+	VcrMode     types.String `tfsdk:"vcr_mode"`
+	VcrCassette types.String `tfsdk:"vcr_cassette"`
 }
 
 func (p *NsxIntervlanRoutingProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -53,23 +100,134 @@ func (p *NsxIntervlanRoutingProvider) Schema(_ context.Context, _ provider.Schem
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"host": schema.StringAttribute{
-				MarkdownDescription: "Hostname or IP address of the NSX endpoint",
+				MarkdownDescription: "Hostname or IP address of the NSX endpoint. Can also be set with the `NSX_MANAGER_HOST` environment variable.",
 				Optional:            true,
 			},
+			"hosts": schema.ListAttribute{
+				MarkdownDescription: "Hostnames or IP addresses of the NSX manager nodes, for clusters without a reachable VIP. " +
+					"Requests go to the first node which is up. When it can't be reached or returns 503, the provider fails over to the next healthy node " +
+					"and stays there until that node fails too. Conflicts with `host`. " +
+					"A comma separated list in the `NSX_MANAGER_HOST` environment variable is used the same way.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
 			"username": schema.StringAttribute{
-				MarkdownDescription: "Username of the NSX endpoint",
+				MarkdownDescription: "Username of the NSX endpoint. Can also be set with the `NSX_USERNAME` environment variable.",
 				Optional:            true,
 			},
 			"password": schema.StringAttribute{
-				MarkdownDescription: "Password of the NSX endpoint",
+				MarkdownDescription: "Password of the NSX endpoint. Can also be set with the `NSX_PASSWORD` environment variable.",
 				Optional:            true,
+				Sensitive:           true,
 			},
 			"insecure": schema.BoolAttribute{
-				MarkdownDescription: "Whether or not the NSX endpoint is insecure",
+				MarkdownDescription: "Whether or not the NSX endpoint is insecure. Can also be set with the `NSX_ALLOW_UNVERIFIED_SSL` environment variable.",
 				Optional:            true,
 			},
 			"debug": schema.BoolAttribute{
-				MarkdownDescription: "Whether or not to log at debug level",
+				MarkdownDescription: "Whether to log the NSX API client's debug messages whatever the Terraform log level is. " +
+					"The client logs to the `nsx_client` subsystem, whose level can also be set with `TF_LOG_PROVIDER_NSX_CLIENT`. " +
+					"Can also be set with the `NSX_DEBUG` environment variable.",
+				Optional: true,
+			},
+			"max_retries": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of times a request is retried after a transient error. Defaults to 4. Can also be set with the `NSX_MAX_RETRIES` environment variable.",
+				Optional:            true,
+			},
+			"retry_min_delay": schema.Int64Attribute{
+				MarkdownDescription: "Delay in milliseconds before the first retry. The delay doubles on every retry. Defaults to 500. Can also be set with the `NSX_RETRY_MIN_DELAY` environment variable.",
+				Optional:            true,
+			},
+			"retry_max_delay": schema.Int64Attribute{
+				MarkdownDescription: "Maximum backoff in milliseconds between retries. A longer Retry-After sent by NSX is still honoured. Defaults to 5000. Can also be set with the `NSX_RETRY_MAX_DELAY` environment variable.",
+				Optional:            true,
+			},
+			"retry_on_status_codes": schema.ListAttribute{
+				MarkdownDescription: "HTTP error status codes, between 400 and 599, which are retried. Defaults to 429 and 503. " +
+					"PATCH and PUT requests are only retried on 429 and 503, when NSX has not applied the request. " +
+					"Can also be set as a comma separated list with the `NSX_RETRY_ON_STATUS_CODES` environment variable.",
+				ElementType: types.Int64Type,
+				Optional:    true,
+			},
+			"request_timeout": schema.Int64Attribute{
+				MarkdownDescription: "Time in seconds a single request to NSX may take before it is abandoned, including reading the response. " +
+					"Retries each get the full timeout. Defaults to 60. Set to 0 to wait indefinitely. " +
+					"Can also be set with the `NSX_REQUEST_TIMEOUT` environment variable.",
+				Optional: true,
+			},
+			"max_requests_per_second": schema.Int64Attribute{
+				MarkdownDescription: "Maximum rate of requests the provider sends to NSX, shared by every resource and data source. " +
+					"Requests over the limit wait for their turn, with bursts of up to one second's worth of requests. " +
+					"Defaults to 100, the per-client limit NSX Manager enforces by default. Set to 0 to disable. " +
+					"Can also be set with the `NSX_MAX_REQUESTS_PER_SECOND` environment variable.",
+				Optional: true,
+			},
+			"max_concurrent_requests": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of requests the provider has in flight to NSX at once. " +
+					"Defaults to 40, the per-client concurrency limit NSX Manager enforces by default. Set to 0 to disable. " +
+					"Can also be set with the `NSX_MAX_CONCURRENT_REQUESTS` environment variable.",
+				Optional: true,
+			},
+			"session_idle_timeout": schema.Int64Attribute{
+				MarkdownDescription: "Time in seconds after which an idle provider logs out of NSX, freeing one of the sessions NSX allows each user. " +
+					"The provider logs in again when it is next used. The session is always closed when the provider stops. " +
+					"Defaults to 0, which keeps the session until then. " +
+					"Can also be set with the `NSX_SESSION_IDLE_TIMEOUT` environment variable.",
+				Optional: true,
+			},
+			"retry_on_revision_conflict": schema.BoolAttribute{
+				MarkdownDescription: "Whether to re-read a segment port and retry the update when it was changed outside Terraform " +
+					"between the last refresh and the update. Defaults to false, which fails the update instead. " +
+					"Can also be set with the `NSX_RETRY_ON_REVISION_CONFLICT` environment variable.",
+				Optional: true,
+			},
+			"client_auth_cert_file": schema.StringAttribute{
+				MarkdownDescription: "Path to the PEM encoded certificate of an NSX principal identity. " +
+					"When set, username and password are not used and no session is created. Can also be set with the `NSX_CLIENT_AUTH_CERT_FILE` environment variable.",
+				Optional: true,
+			},
+			"client_auth_key_file": schema.StringAttribute{
+				MarkdownDescription: "Path to the PEM encoded private key of the principal identity certificate. Can also be set with the `NSX_CLIENT_AUTH_KEY_FILE` environment variable.",
+				Optional:            true,
+			},
+			"client_auth_cert": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded certificate of an NSX principal identity. Conflicts with `client_auth_cert_file`. Can also be set with the `NSX_CLIENT_AUTH_CERT` environment variable.",
+				Optional:            true,
+			},
+			"client_auth_key": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded private key of the principal identity certificate. Conflicts with `client_auth_key_file`. Can also be set with the `NSX_CLIENT_AUTH_KEY` environment variable.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"ca_file": schema.StringAttribute{
+				MarkdownDescription: "Path to a PEM encoded CA bundle used to verify the NSX manager certificate instead of the system trust store. Can also be set with the `NSX_CA_FILE` environment variable.",
+				Optional:            true,
+			},
+			"ca_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA bundle used to verify the NSX manager certificate. Conflicts with `ca_file`. Can also be set with the `NSX_CA` environment variable.",
+				Optional:            true,
+			},
+			"tls_server_name": schema.StringAttribute{
+				MarkdownDescription: "Name used to verify the NSX manager certificate, when it differs from `host`. Can also be set with the `NSX_TLS_SERVER_NAME` environment variable.",
+				Optional:            true,
+			},
+			"min_tls_version": schema.StringAttribute{
+				MarkdownDescription: "Minimum TLS version accepted from the NSX manager. One of 1.0, 1.1, 1.2 or 1.3. Can also be set with the `NSX_MIN_TLS_VERSION` environment variable.",
+				Optional:            true,
+			},
+			"manager_thumbprint": schema.StringAttribute{
+				MarkdownDescription: "SHA-256 thumbprint of the NSX manager certificate, in hex with or without colons. " +
+					"When set, the manager is trusted if its certificate matches the thumbprint, even if it is not signed by a trusted CA. Can also be set with the `NSX_MANAGER_THUMBPRINT` environment variable.",
+				Optional: true,
+			},
+			"vcr_mode": schema.StringAttribute{
+				MarkdownDescription: "Set to `record` to save every exchange with the NSX manager to `vcr_cassette`, or to `replay` to answer requests " +
+					"from it without contacting NSX. Credentials, cookies and XSRF tokens are scrubbed from the cassette. Intended for debugging. " +
+					"Can also be set with the `NSX_VCR_MODE` environment variable.",
+				Optional: true,
+			},
+			"vcr_cassette": schema.StringAttribute{
+				MarkdownDescription: "Path of the cassette file used by `vcr_mode`. Can also be set with the `NSX_VCR_CASSETTE` environment variable.",
 				Optional:            true,
 			},
 		},
@@ -85,85 +243,393 @@ func (p *NsxIntervlanRoutingProvider) Configure(ctx context.Context, req provide
 		return
 	}
 
+	connection := []struct {
+		attr  string
+		value types.String
+	}{
+		{"host", data.Host},
+		{"username", data.Username},
+		{"password", data.Password},
+	}
+	for _, c := range connection {
+		if c.value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(c.attr),
+				"Unknown NSX "+c.attr,
+				"The provider cannot create the NSX API client as there is an unknown configuration value for "+c.attr+". "+
+					"Either set the value statically in the configuration, or use an environment variable.",
+			)
+		}
+	}
+	if data.Hosts.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("hosts"),
+			"Unknown NSX hosts",
+			"The provider cannot create the NSX API client as there is an unknown configuration value for hosts. "+
+				"Set the value statically in the configuration.",
+		)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var managers []string
+	if !data.Hosts.IsNull() {
+		resp.Diagnostics.Append(data.Hosts.ElementsAs(ctx, &managers, false)...)
+		if !data.Host.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("hosts"),
+				"Conflicting NSX Manager Hosts",
+				"Set either host or hosts, not both.",
+			)
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	applyEnvironment(&data, &resp.Diagnostics)
+	if len(managers) == 0 {
+		for _, host := range strings.Split(data.Host.ValueString(), ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				managers = append(managers, host)
+			}
+		}
+	}
+
+	certPEM := loadPEM(&resp.Diagnostics, data.ClientAuthCertFile, "client_auth_cert_file", data.ClientAuthCert, "client_auth_cert")
+	keyPEM := loadPEM(&resp.Diagnostics, data.ClientAuthKeyFile, "client_auth_key_file", data.ClientAuthKey, "client_auth_key")
+	caPEM := loadPEM(&resp.Diagnostics, data.CaFile, "ca_file", data.CaPem, "ca_pem")
+	if (certPEM == nil) != (keyPEM == nil) {
+		resp.Diagnostics.AddError(
+			"Incomplete client certificate configuration",
+			"Principal identity authentication requires both a certificate and a private key. "+
+				"Set client_auth_cert_file and client_auth_key_file, or client_auth_cert and client_auth_key.",
+		)
+	}
+	if caPEM != nil {
+		if _, err := client.ParseCACertificates(caPEM); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				pemAttribute(data.CaFile, "ca_file", "ca_pem"),
+				"Invalid CA bundle",
+				"The CA bundle must contain at least one PEM encoded certificate: "+err.Error(),
+			)
+		}
+	}
+	if data.ManagerThumbprint.ValueString() != "" {
+		if _, err := client.ParseCertificateThumbprint(data.ManagerThumbprint.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("manager_thumbprint"),
+				"Invalid manager_thumbprint value",
+				"The manager_thumbprint value must be the SHA-256 thumbprint of the NSX manager certificate in hex, with or without colons: "+err.Error(),
+			)
+		}
+	}
+	if certPEM != nil && keyPEM != nil {
+		validateClientCertificate(&resp.Diagnostics,
+			certPEM, pemAttribute(data.ClientAuthCertFile, "client_auth_cert_file", "client_auth_cert"),
+			keyPEM, pemAttribute(data.ClientAuthKeyFile, "client_auth_key_file", "client_auth_key"))
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	certificateAuth := certPEM != nil
+
+	vcrMode := client.VCRMode(data.VcrMode.ValueString())
+	switch vcrMode {
+	case "", client.VCRModeRecord, client.VCRModeReplay:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("vcr_mode"),
+			"Invalid vcr_mode value",
+			fmt.Sprintf("The vcr_mode value must be %q or %q, got: %s", client.VCRModeRecord, client.VCRModeReplay, vcrMode),
+		)
+	}
+	if vcrMode != "" && data.VcrCassette.ValueString() == "" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("vcr_cassette"),
+			"Missing vcr_cassette value",
+			"A cassette file must be set with vcr_cassette or the "+envVcrCassette+" environment variable when vcr_mode is set.",
+		)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// A replayed login never reaches NSX, so a cassette can be replayed
+	// without the credentials it was recorded with.
+	credentialsRequired := !certificateAuth && vcrMode != client.VCRModeReplay
+
 	// Configuration values are now available.
-	if data.Host.IsNull() {
-		resp.Diagnostics.AddAttributeWarning(
+	if len(managers) == 0 {
+		resp.Diagnostics.AddAttributeError(
 			path.Root("host"),
-			"Missing NSX Manager API Hostname (using default value: 127.0.0.1)",
-			"The provider is using a default value as there is a missing or empty value for the NSX-T Manager API hostname. "+
-				"Set the host value in the configuration. "+
-				"If either is already set, ensure the value is not empty.",
+			"Missing NSX Manager API Hostname",
+			"The provider cannot create the NSX API client as there is a missing or empty value for the NSX Manager API hostname. "+
+				"Set the host or hosts value in the configuration or use the "+envManagerHost+" environment variable.",
 		)
-		data.Host = types.StringValue("127.0.0.1")
 	}
-	if data.Username.IsNull() {
-		resp.Diagnostics.AddAttributeWarning(
+	if data.Username.ValueString() == "" && credentialsRequired {
+		resp.Diagnostics.AddAttributeError(
 			path.Root("username"),
-			"Missing NSX API username (using default value: admin)",
-			"The provider is using a default value as there is a missing or empty value for the NSX-T API username. "+
-				"Set the username value in the configuration. "+
-				"If either is already set, ensure the value is not empty.",
+			"Missing NSX API username",
+			"The provider cannot create the NSX API client as there is a missing or empty value for the NSX API username. "+
+				"Set the username value in the configuration or use the "+envUsername+" environment variable, "+
+				"or configure a principal identity certificate.",
 		)
-		data.Username = types.StringValue("admin")
 	}
-	if data.Password.IsNull() {
-		resp.Diagnostics.AddAttributeWarning(
+	if data.Password.ValueString() == "" && credentialsRequired {
+		resp.Diagnostics.AddAttributeError(
 			path.Root("password"),
-			"Missing NSX API port (using default value: password)",
-			"The provider is using a default value as there is a missing or empty value for the NSX-T API password. "+
-				"Set the password value in the configuration. "+
-				"If either is already set, ensure the value is not empty.",
+			"Missing NSX API password",
+			"The provider cannot create the NSX API client as there is a missing or empty value for the NSX API password. "+
+				"Set the password value in the configuration or use the "+envPassword+" environment variable, "+
+				"or configure a principal identity certificate.",
+		)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	retryPolicy := client.DefaultRetryPolicy()
+	if !data.MaxRetries.IsNull() {
+		retryPolicy.MaxRetries = int(data.MaxRetries.ValueInt64())
+	}
+	if !data.RetryMinDelay.IsNull() {
+		retryPolicy.MinDelay = time.Duration(data.RetryMinDelay.ValueInt64()) * time.Millisecond
+	}
+	if !data.RetryMaxDelay.IsNull() {
+		retryPolicy.MaxDelay = time.Duration(data.RetryMaxDelay.ValueInt64()) * time.Millisecond
+	}
+	if !data.RetryOnStatusCodes.IsNull() {
+		var codes []int64
+		resp.Diagnostics.Append(data.RetryOnStatusCodes.ElementsAs(ctx, &codes, false)...)
+		retryPolicy.RetryOnStatusCodes = nil
+		for _, code := range codes {
+			retryPolicy.RetryOnStatusCodes = append(retryPolicy.RetryOnStatusCodes, int(code))
+		}
+	}
+	if retryPolicy.MaxRetries < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_retries"),
+			"Invalid max_retries value",
+			"The max_retries value must not be negative.",
+		)
+	}
+	for _, code := range retryPolicy.RetryOnStatusCodes {
+		if code < 400 || code > 599 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("retry_on_status_codes"),
+				"Invalid retry_on_status_codes value",
+				fmt.Sprintf("The retry_on_status_codes value must only contain HTTP error status codes between 400 and 599, got: %d", code),
+			)
+		}
+	}
+	if retryPolicy.MinDelay < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("retry_min_delay"),
+			"Invalid retry_min_delay value",
+			"The retry_min_delay value must not be negative.",
+		)
+	}
+	if retryPolicy.MaxDelay < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("retry_max_delay"),
+			"Invalid retry_max_delay value",
+			"The retry_max_delay value must not be negative.",
+		)
+	}
+	if retryPolicy.MinDelay >= 0 && retryPolicy.MaxDelay >= 0 && retryPolicy.MinDelay > retryPolicy.MaxDelay {
+		// Point at the delays which were set, since the other one may be the
+		// default.
+		detail := fmt.Sprintf("The retry_min_delay (%d ms) must not be greater than retry_max_delay (%d ms).",
+			retryPolicy.MinDelay.Milliseconds(), retryPolicy.MaxDelay.Milliseconds())
+		if !data.RetryMinDelay.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("retry_min_delay"), "Invalid retry_min_delay value", detail)
+		}
+		if !data.RetryMaxDelay.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("retry_max_delay"), "Invalid retry_max_delay value", detail)
+		}
+	}
+	requestTimeout := client.DefaultTimeout
+	if !data.RequestTimeout.IsNull() {
+		requestTimeout = time.Duration(data.RequestTimeout.ValueInt64()) * time.Second
+	}
+	if requestTimeout < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("request_timeout"),
+			"Invalid request_timeout value",
+			"The request_timeout value must not be negative.",
 		)
-		data.Password = types.StringValue("password")
 	}
-	if data.Insecure.IsUnknown() {
-		resp.Diagnostics.AddAttributeWarning(
-			path.Root("insecure"),
-			"Missing NSX Manager API Insecure (using default value: false)",
-			"The provider is using a default value as there is a missing or empty flag for the NSX-T Manager API insecure. "+
-				"Set the insecure flag in the configuration. "+
-				"If either is already set, ensure the value is not empty.",
+	maxRequestsPerSecond := int64(client.DefaultMaxRequestsPerSecond)
+	if !data.MaxRequestsPerSecond.IsNull() {
+		maxRequestsPerSecond = data.MaxRequestsPerSecond.ValueInt64()
+	}
+	if maxRequestsPerSecond < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_requests_per_second"),
+			"Invalid max_requests_per_second value",
+			"The max_requests_per_second value must not be negative.",
 		)
-		data.Insecure = types.BoolValue(false)
 	}
-	if data.Debug.IsUnknown() {
-		resp.Diagnostics.AddAttributeWarning(
-			path.Root("debug"),
-			"Missing the debug attribute (using default value: false)",
-			"The provider is using a default value as there is a missing or empty value for debug flag. "+
-				"Set the debug flag in the configuration. "+
-				"If either is already set, ensure the value is not empty.",
+	maxConcurrentRequests := int64(client.DefaultMaxConcurrentRequests)
+	if !data.MaxConcurrentRequests.IsNull() {
+		maxConcurrentRequests = data.MaxConcurrentRequests.ValueInt64()
+	}
+	if maxConcurrentRequests < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_concurrent_requests"),
+			"Invalid max_concurrent_requests value",
+			"The max_concurrent_requests value must not be negative.",
 		)
-		data.Debug = types.BoolValue(false)
+	}
+	sessionIdleTimeout := time.Duration(data.SessionIdleTimeout.ValueInt64()) * time.Second
+	if sessionIdleTimeout < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("session_idle_timeout"),
+			"Invalid session_idle_timeout value",
+			"The session_idle_timeout value must not be negative.",
+		)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	opts := []client.NsxClientOption{
+		client.WithRetryPolicy(retryPolicy),
+		client.WithTimeout(requestTimeout),
+		client.WithRateLimit(float64(maxRequestsPerSecond), int(maxConcurrentRequests)),
+		client.WithIdleLogout(sessionIdleTimeout),
+		client.WithUserAgent("terraform-provider-nsx-intervlan-routing/" + p.version),
+	}
+	if certificateAuth {
+		opts = append(opts, client.WithClientCertificate(certPEM, keyPEM))
+	}
+	if caPEM != nil {
+		opts = append(opts, client.WithCACertificates(caPEM))
+	}
+	if data.TlsServerName.ValueString() != "" {
+		opts = append(opts, client.WithTLSServerName(data.TlsServerName.ValueString()))
+	}
+	if data.MinTlsVersion.ValueString() != "" {
+		version, err := client.ParseTLSVersion(data.MinTlsVersion.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("min_tls_version"), "Invalid min_tls_version value", err.Error())
+			return
+		}
+		opts = append(opts, client.WithMinTLSVersion(version))
+	}
+	if data.ManagerThumbprint.ValueString() != "" {
+		opts = append(opts, client.WithCertificateThumbprint(data.ManagerThumbprint.ValueString()))
+	}
+	if len(managers) > 1 {
+		opts = append(opts, client.WithFailoverServers(managers[1:]...))
+	}
+	if vcrMode != "" {
+		opts = append(opts, client.WithVCR(vcrMode, data.VcrCassette.ValueString()))
 	}
 
 	// Example client configuration for data sources and resources
 	cl, err := client.NewClient(
-		data.Host.ValueString(),
+		ctx,
+		managers[0],
 		data.Username.ValueString(),
 		data.Password.ValueString(),
 		data.Insecure.ValueBool(),
-		data.Debug.ValueBool())
+		data.Debug.ValueBool(),
+		opts...)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to create an instance of the API Client.",
-			"The provider has failed to instantiate the API Client at line 117 of the provider.tf."+
-				"Please check the instantiation of the client to ensure the params are correct.")
-		panic("Failed to create an instance of the API Client. Error is: " + err.Error())
+		addConfigureError(&resp.Diagnostics, strings.Join(managers, ", "), err)
+		return
 	}
+	p.clientsMu.Lock()
+	p.clients = append(p.clients, cl)
+	p.clientsMu.Unlock()
 
 	providerData := &NsxIntervlanRoutingProviderData{
-		Client:   *cl,
-		Host:     data.Host.ValueString(),
+		Client:   cl,
+		Host:     managers[0],
 		Username: data.Username.ValueString(),
 		Password: data.Password.ValueString(),
 		Insecure: data.Insecure.ValueBool(),
 		Debug:    data.Debug.ValueBool(),
+
+		RetryOnRevisionConflict: data.RetryOnRevisionConflict.ValueBool(),
 	}
 	resp.DataSourceData = providerData
 	resp.ResourceData = providerData
 }
 
+// loadPEM returns the PEM data configured either as a file path or inline. It
+// returns nil when neither attribute is set.
+func loadPEM(diags *diag.Diagnostics, file types.String, fileAttr string, inline types.String, inlineAttr string) []byte {
+	hasFile := !file.IsNull() && file.ValueString() != ""
+	hasInline := !inline.IsNull() && inline.ValueString() != ""
+
+	switch {
+	case hasFile && hasInline:
+		diags.AddAttributeError(
+			path.Root(inlineAttr),
+			"Conflicting PEM configuration",
+			"Only one of "+fileAttr+" and "+inlineAttr+" can be set.",
+		)
+		return nil
+	case hasFile:
+		data, err := os.ReadFile(file.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root(fileAttr),
+				"Unable to read PEM file",
+				"The file "+file.ValueString()+" could not be read: "+err.Error(),
+			)
+			return nil
+		}
+		return data
+	case hasInline:
+		return []byte(inline.ValueString())
+	}
+	return nil
+}
+
+// pemAttribute returns the path of the attribute PEM data was loaded from:
+// the file attribute if it is set, or else the inline one.
+func pemAttribute(file types.String, fileAttr string, inlineAttr string) path.Path {
+	if !file.IsNull() && file.ValueString() != "" {
+		return path.Root(fileAttr)
+	}
+	return path.Root(inlineAttr)
+}
+
+// validateClientCertificate checks that the principal identity certificate
+// can be parsed and that the key belongs to it, so that a mistake is reported
+// on the attribute which holds it rather than as a connection failure.
+func validateClientCertificate(diags *diag.Diagnostics, certPEM []byte, certPath path.Path, keyPEM []byte, keyPath path.Path) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		diags.AddAttributeError(
+			certPath,
+			"Invalid client certificate",
+			"The principal identity certificate must be a PEM encoded X.509 certificate.",
+		)
+		return
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		diags.AddAttributeError(
+			certPath,
+			"Invalid client certificate",
+			"The principal identity certificate could not be parsed: "+err.Error(),
+		)
+		return
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		diags.AddAttributeError(
+			keyPath,
+			"Invalid client certificate key",
+			"The private key could not be loaded or does not match the principal identity certificate: "+err.Error(),
+		)
+	}
+}
+
 func (p *NsxIntervlanRoutingProvider) Resources(_ context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewSegmentPortResource,
@@ -176,6 +642,22 @@ func (p *NsxIntervlanRoutingProvider) DataSources(_ context.Context) []func() da
 	}
 }
 
+// Close logs out of the NSX sessions opened by the provider. It is called when
+// the provider server stops.
+func (p *NsxIntervlanRoutingProvider) Close() error {
+	p.clientsMu.Lock()
+	defer p.clientsMu.Unlock()
+
+	var errs []error
+	for _, cl := range p.clients {
+		if err := cl.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	p.clients = nil
+	return errors.Join(errs...)
+}
+
 func New(version string) func() provider.Provider {
 	return func() provider.Provider {
 		return &NsxIntervlanRoutingProvider{
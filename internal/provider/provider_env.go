@@ -0,0 +1,151 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Environment variables read when the matching provider attribute is not set
+// in the configuration.
+const (
+	envManagerHost        = "NSX_MANAGER_HOST"
+	envUsername           = "NSX_USERNAME"
+	envPassword           = "NSX_PASSWORD"
+	envAllowUnverifiedSSL = "NSX_ALLOW_UNVERIFIED_SSL"
+	envDebug              = "NSX_DEBUG"
+	envCaFile             = "NSX_CA_FILE"
+	envCaPem              = "NSX_CA"
+	envTlsServerName      = "NSX_TLS_SERVER_NAME"
+	envMinTlsVersion      = "NSX_MIN_TLS_VERSION"
+	envManagerThumbprint  = "NSX_MANAGER_THUMBPRINT"
+	envClientAuthCertFile = "NSX_CLIENT_AUTH_CERT_FILE"
+	envClientAuthKeyFile  = "NSX_CLIENT_AUTH_KEY_FILE"
+	envClientAuthCert     = "NSX_CLIENT_AUTH_CERT"
+	envClientAuthKey      = "NSX_CLIENT_AUTH_KEY"
+	envMaxRetries         = "NSX_MAX_RETRIES"
+	envRetryMinDelay      = "NSX_RETRY_MIN_DELAY"
+	envRetryMaxDelay      = "NSX_RETRY_MAX_DELAY"
+	envRetryOnStatusCodes = "NSX_RETRY_ON_STATUS_CODES"
+	envRetryOnConflict    = "NSX_RETRY_ON_REVISION_CONFLICT"
+	envRequestTimeout     = "NSX_REQUEST_TIMEOUT"
+	envMaxRequestsPerSec  = "NSX_MAX_REQUESTS_PER_SECOND"
+	envMaxConcurrent      = "NSX_MAX_CONCURRENT_REQUESTS"
+	envSessionIdleTimeout = "NSX_SESSION_IDLE_TIMEOUT"
+	envVcrMode            = "NSX_VCR_MODE"
+	envVcrCassette        = "NSX_VCR_CASSETTE"
+)
+
+// applyEnvironment fills every attribute which is not set in the provider
+// configuration from its environment variable.
+func applyEnvironment(data *NsxIntervlanRoutingProviderModel, diags *diag.Diagnostics) {
+	data.Host = stringFromEnv(data.Host, envManagerHost)
+	data.Username = stringFromEnv(data.Username, envUsername)
+	data.Password = stringFromEnv(data.Password, envPassword)
+	data.Insecure = boolFromEnv(diags, data.Insecure, "insecure", envAllowUnverifiedSSL)
+	data.Debug = boolFromEnv(diags, data.Debug, "debug", envDebug)
+
+	data.CaFile = stringFromEnv(data.CaFile, envCaFile)
+	data.CaPem = stringFromEnv(data.CaPem, envCaPem)
+	data.TlsServerName = stringFromEnv(data.TlsServerName, envTlsServerName)
+	data.MinTlsVersion = stringFromEnv(data.MinTlsVersion, envMinTlsVersion)
+	data.ManagerThumbprint = stringFromEnv(data.ManagerThumbprint, envManagerThumbprint)
+
+	data.ClientAuthCertFile = stringFromEnv(data.ClientAuthCertFile, envClientAuthCertFile)
+	data.ClientAuthKeyFile = stringFromEnv(data.ClientAuthKeyFile, envClientAuthKeyFile)
+	data.ClientAuthCert = stringFromEnv(data.ClientAuthCert, envClientAuthCert)
+	data.ClientAuthKey = stringFromEnv(data.ClientAuthKey, envClientAuthKey)
+
+	data.MaxRetries = int64FromEnv(diags, data.MaxRetries, "max_retries", envMaxRetries)
+	data.RetryMinDelay = int64FromEnv(diags, data.RetryMinDelay, "retry_min_delay", envRetryMinDelay)
+	data.RetryMaxDelay = int64FromEnv(diags, data.RetryMaxDelay, "retry_max_delay", envRetryMaxDelay)
+	data.RetryOnStatusCodes = int64ListFromEnv(diags, data.RetryOnStatusCodes, "retry_on_status_codes", envRetryOnStatusCodes)
+	data.RetryOnRevisionConflict = boolFromEnv(diags, data.RetryOnRevisionConflict, "retry_on_revision_conflict", envRetryOnConflict)
+	data.RequestTimeout = int64FromEnv(diags, data.RequestTimeout, "request_timeout", envRequestTimeout)
+	data.MaxRequestsPerSecond = int64FromEnv(diags, data.MaxRequestsPerSecond, "max_requests_per_second", envMaxRequestsPerSec)
+	data.MaxConcurrentRequests = int64FromEnv(diags, data.MaxConcurrentRequests, "max_concurrent_requests", envMaxConcurrent)
+	data.SessionIdleTimeout = int64FromEnv(diags, data.SessionIdleTimeout, "session_idle_timeout", envSessionIdleTimeout)
+
+	data.VcrMode = stringFromEnv(data.VcrMode, envVcrMode)
+	data.VcrCassette = stringFromEnv(data.VcrCassette, envVcrCassette)
+}
+
+func stringFromEnv(value types.String, env string) types.String {
+	if !value.IsNull() {
+		return value
+	}
+	if v, ok := os.LookupEnv(env); ok && v != "" {
+		return types.StringValue(v)
+	}
+	return value
+}
+
+func boolFromEnv(diags *diag.Diagnostics, value types.Bool, attr string, env string) types.Bool {
+	v, ok := os.LookupEnv(env)
+	if !value.IsNull() || !ok || v == "" {
+		return value
+	}
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root(attr),
+			"Invalid "+env+" environment variable",
+			"The "+env+" environment variable must be a boolean, got: "+v,
+		)
+		return value
+	}
+	return types.BoolValue(parsed)
+}
+
+func int64FromEnv(diags *diag.Diagnostics, value types.Int64, attr string, env string) types.Int64 {
+	v, ok := os.LookupEnv(env)
+	if !value.IsNull() || !ok || v == "" {
+		return value
+	}
+	parsed, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root(attr),
+			"Invalid "+env+" environment variable",
+			"The "+env+" environment variable must be an integer, got: "+v,
+		)
+		return value
+	}
+	return types.Int64Value(parsed)
+}
+
+// int64ListFromEnv reads a comma separated list of integers, in the same form
+// as the list of hosts in NSX_MANAGER_HOST.
+func int64ListFromEnv(diags *diag.Diagnostics, value types.List, attribute string, env string) types.List {
+	v, ok := os.LookupEnv(env)
+	if !value.IsNull() || !ok || v == "" {
+		return value
+	}
+	var elements []attr.Value
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root(attribute),
+				"Invalid "+env+" environment variable",
+				"The "+env+" environment variable must be a comma separated list of integers, got: "+v,
+			)
+			return value
+		}
+		elements = append(elements, types.Int64Value(parsed))
+	}
+	list, d := types.ListValue(types.Int64Type, elements)
+	diags.Append(d...)
+	return list
+}
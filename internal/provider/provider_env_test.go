@@ -0,0 +1,87 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestApplyEnvironment(t *testing.T) {
+	t.Setenv(envManagerHost, "nsx.example.com")
+	t.Setenv(envUsername, "env-user")
+	t.Setenv(envPassword, "env-password")
+	t.Setenv(envAllowUnverifiedSSL, "true")
+	t.Setenv(envMaxRetries, "7")
+	t.Setenv(envMaxRequestsPerSec, "25")
+	t.Setenv(envRetryOnStatusCodes, "429, 502,503")
+	t.Setenv(envRetryOnConflict, "true")
+
+	data := NsxIntervlanRoutingProviderModel{
+		Username: types.StringValue("config-user"),
+	}
+	var diags diag.Diagnostics
+	applyEnvironment(&data, &diags)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if data.Host.ValueString() != "nsx.example.com" {
+		t.Errorf("expected host from environment, got %q", data.Host.ValueString())
+	}
+	if data.Username.ValueString() != "config-user" {
+		t.Errorf("expected configured username to take precedence, got %q", data.Username.ValueString())
+	}
+	if data.Password.ValueString() != "env-password" {
+		t.Errorf("expected password from environment, got %q", data.Password.ValueString())
+	}
+	if !data.Insecure.ValueBool() {
+		t.Error("expected insecure from environment")
+	}
+	if data.MaxRetries.ValueInt64() != 7 {
+		t.Errorf("expected max_retries from environment, got %d", data.MaxRetries.ValueInt64())
+	}
+	if data.MaxRequestsPerSecond.ValueInt64() != 25 {
+		t.Errorf("expected max_requests_per_second from environment, got %d", data.MaxRequestsPerSecond.ValueInt64())
+	}
+	wantCodes := types.ListValueMust(types.Int64Type, []attr.Value{
+		types.Int64Value(429), types.Int64Value(502), types.Int64Value(503),
+	})
+	if !data.RetryOnStatusCodes.Equal(wantCodes) {
+		t.Errorf("expected retry_on_status_codes from environment, got %s", data.RetryOnStatusCodes)
+	}
+	if !data.RetryOnRevisionConflict.ValueBool() {
+		t.Error("expected retry_on_revision_conflict from environment")
+	}
+}
+
+func TestApplyEnvironmentInvalidBool(t *testing.T) {
+	t.Setenv(envAllowUnverifiedSSL, "sometimes")
+
+	var data NsxIntervlanRoutingProviderModel
+	var diags diag.Diagnostics
+	applyEnvironment(&data, &diags)
+
+	if !diags.HasError() {
+		t.Fatal("expected an error for an invalid boolean")
+	}
+}
+
+func TestApplyEnvironmentInvalidStatusCodes(t *testing.T) {
+	t.Setenv(envRetryOnStatusCodes, "429,busy")
+
+	var data NsxIntervlanRoutingProviderModel
+	var diags diag.Diagnostics
+	applyEnvironment(&data, &diags)
+
+	if !diags.HasError() {
+		t.Fatal("expected an error for an invalid status code")
+	}
+	if !data.RetryOnStatusCodes.IsNull() {
+		t.Errorf("expected retry_on_status_codes to stay unset, got %s", data.RetryOnStatusCodes)
+	}
+}
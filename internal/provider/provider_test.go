@@ -4,10 +4,31 @@
 package provider
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"terraform-provider-nsx-intervlan-routing/internal/nsxfake"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 // testAccProtoV6ProviderFactories is used to instantiate a provider during acceptance testing.
@@ -31,3 +52,242 @@ func testAccPreCheck(_ *testing.T) {
 	// about the appropriate environment variables being set are common to see in a pre-check
 	// function.
 }
+
+// testAccNsxFake starts an in-memory NSX manager for the duration of the test
+// so acceptance tests can run without a real one.
+func testAccNsxFake(t *testing.T) *nsxfake.Server {
+	t.Helper()
+
+	server := nsxfake.NewServer()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testAccProviderConfig returns a provider block pointing at server.
+func testAccProviderConfig(server *nsxfake.Server) string {
+	return fmt.Sprintf(`
+provider "nsx-intervlan-routing" {
+  host     = %q
+  username = %q
+  password = %q
+}
+`, server.URL, nsxfake.DefaultUsername, nsxfake.DefaultPassword)
+}
+
+// testProviderModel returns a provider configuration with credentials and
+// nothing else set.
+func testProviderModel() NsxIntervlanRoutingProviderModel {
+	return NsxIntervlanRoutingProviderModel{
+		Host:               types.StringValue("nsx.example.com"),
+		Hosts:              types.ListNull(types.StringType),
+		Username:           types.StringValue("admin"),
+		Password:           types.StringValue("secret"),
+		RetryOnStatusCodes: types.ListNull(types.Int64Type),
+	}
+}
+
+// testProviderConfigure runs Configure with model as the provider
+// configuration and returns the paths of its error diagnostics.
+func testProviderConfigure(t *testing.T, model NsxIntervlanRoutingProviderModel) []path.Path {
+	t.Helper()
+	ctx := context.Background()
+
+	p := New("test")()
+	var schemaResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+	plan := tfsdk.Plan{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}
+	if diags := plan.Set(ctx, model); diags.HasError() {
+		t.Fatalf("unable to build provider config: %v", diags)
+	}
+
+	var resp provider.ConfigureResponse
+	p.Configure(ctx, provider.ConfigureRequest{Config: tfsdk.Config{Schema: plan.Schema, Raw: plan.Raw}}, &resp)
+
+	var paths []path.Path
+	for _, d := range resp.Diagnostics.Errors() {
+		withPath, ok := d.(diag.DiagnosticWithPath)
+		if !ok {
+			t.Fatalf("expected an attribute error, got %v", d)
+		}
+		paths = append(paths, withPath.Path())
+	}
+	return paths
+}
+
+// assertErrorPaths checks that errors were reported on exactly the wanted
+// attributes, in order.
+func assertErrorPaths(t *testing.T, got []path.Path, want []path.Path) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected errors on %v, got %v", want, got)
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("expected error %d on %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestProviderConfigureRetrySettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		configure  func(*NsxIntervlanRoutingProviderModel)
+		wantErrors []path.Path
+	}{
+		{
+			name: "status code out of range",
+			configure: func(m *NsxIntervlanRoutingProviderModel) {
+				m.RetryOnStatusCodes = types.ListValueMust(types.Int64Type, []attr.Value{
+					types.Int64Value(429), types.Int64Value(200),
+				})
+			},
+			wantErrors: []path.Path{path.Root("retry_on_status_codes")},
+		},
+		{
+			name: "max delay below default min delay",
+			configure: func(m *NsxIntervlanRoutingProviderModel) {
+				m.RetryMaxDelay = types.Int64Value(100)
+			},
+			wantErrors: []path.Path{path.Root("retry_max_delay")},
+		},
+		{
+			name: "min delay above default max delay",
+			configure: func(m *NsxIntervlanRoutingProviderModel) {
+				m.RetryMinDelay = types.Int64Value(10000)
+			},
+			wantErrors: []path.Path{path.Root("retry_min_delay")},
+		},
+		{
+			name: "min delay above max delay",
+			configure: func(m *NsxIntervlanRoutingProviderModel) {
+				m.RetryMinDelay = types.Int64Value(2000)
+				m.RetryMaxDelay = types.Int64Value(1000)
+			},
+			wantErrors: []path.Path{path.Root("retry_min_delay"), path.Root("retry_max_delay")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := testProviderModel()
+			tt.configure(&model)
+
+			assertErrorPaths(t, testProviderConfigure(t, model), tt.wantErrors)
+		})
+	}
+}
+
+// testCertificate returns a self-signed PEM encoded certificate and key.
+func testCertificate(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "terraform"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestProviderConfigureClientCertificate(t *testing.T) {
+	cert, key := testCertificate(t)
+	_, otherKey := testCertificate(t)
+
+	tests := []struct {
+		name       string
+		cert, key  []byte
+		wantErrors []path.Path
+	}{
+		{
+			name:       "unparsable certificate",
+			cert:       []byte("not a certificate"),
+			key:        key,
+			wantErrors: []path.Path{path.Root("client_auth_cert")},
+		},
+		{
+			name:       "unparsable key",
+			cert:       cert,
+			key:        []byte("not a key"),
+			wantErrors: []path.Path{path.Root("client_auth_key")},
+		},
+		{
+			name:       "key of another certificate",
+			cert:       cert,
+			key:        otherKey,
+			wantErrors: []path.Path{path.Root("client_auth_key")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := testProviderModel()
+			model.ClientAuthCert = types.StringValue(string(tt.cert))
+			model.ClientAuthKey = types.StringValue(string(tt.key))
+
+			assertErrorPaths(t, testProviderConfigure(t, model), tt.wantErrors)
+		})
+	}
+}
+
+func TestProviderConfigureServerVerification(t *testing.T) {
+	tests := []struct {
+		name       string
+		configure  func(*NsxIntervlanRoutingProviderModel)
+		wantErrors []path.Path
+	}{
+		{
+			name: "CA bundle without certificates",
+			configure: func(m *NsxIntervlanRoutingProviderModel) {
+				m.CaPem = types.StringValue("not a certificate")
+			},
+			wantErrors: []path.Path{path.Root("ca_pem")},
+		},
+		{
+			name: "CA file without certificates",
+			configure: func(m *NsxIntervlanRoutingProviderModel) {
+				file := filepath.Join(t.TempDir(), "ca.pem")
+				if err := os.WriteFile(file, []byte("not a certificate"), 0o600); err != nil {
+					t.Fatalf("failed to write CA file: %s", err)
+				}
+				m.CaFile = types.StringValue(file)
+			},
+			wantErrors: []path.Path{path.Root("ca_file")},
+		},
+		{
+			name: "thumbprint of the wrong length",
+			configure: func(m *NsxIntervlanRoutingProviderModel) {
+				m.ManagerThumbprint = types.StringValue("AB:CD:EF")
+			},
+			wantErrors: []path.Path{path.Root("manager_thumbprint")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := testProviderModel()
+			tt.configure(&model)
+
+			assertErrorPaths(t, testProviderConfigure(t, model), tt.wantErrors)
+		})
+	}
+}
@@ -5,19 +5,24 @@ package provider
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"strconv"
+	"strings"
 	"terraform-provider-nsx-intervlan-routing/client"
 	"terraform-provider-nsx-intervlan-routing/helpers"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/identityschema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
@@ -26,6 +31,20 @@ var (
 	_ resource.ResourceWithConfigure   = &SegmentPortResource{}
 	_ resource.Resource                = &SegmentPortResource{}
 	_ resource.ResourceWithImportState = &SegmentPortResource{}
+
+	_ resource.ResourceWithConfigValidators = &SegmentPortResource{}
+	_ resource.ResourceWithIdentity         = &SegmentPortResource{}
+)
+
+// Default deadlines for each operation on a segment port, covering every
+// request to NSX it makes including retries and the read-back after a write.
+// They can be changed with the resource's timeouts block, and are derived from
+// the context Terraform passes in, so an earlier deadline still applies.
+const (
+	segmentPortCreateTimeout = 10 * time.Minute
+	segmentPortReadTimeout   = 5 * time.Minute
+	segmentPortUpdateTimeout = 10 * time.Minute
+	segmentPortDeleteTimeout = 10 * time.Minute
 )
 
 func NewSegmentPortResource() resource.Resource {
@@ -33,13 +52,25 @@ func NewSegmentPortResource() resource.Resource {
 }
 
 type SegmentPortResource struct {
-	client client.Client
+	client client.NsxClientInterface
+	// retryOnRevisionConflict re-reads the port and retries an update once
+	// when NSX rejects it because the port changed outside Terraform.
+	retryOnRevisionConflict bool
 }
 
 type SegmentPortResourceModel struct {
 	SegmentId   types.String         `tfsdk:"segment_id"`
 	PortId      types.String         `tfsdk:"port_id"`
 	SegmentPort *helpers.SegmentPort `tfsdk:"segment_port"`
+	Timeouts    timeouts.Value       `tfsdk:"timeouts"`
+}
+
+// SegmentPortIdentityModel is the resource identity of a segment port. Context
+// is null for segments directly under /infra.
+type SegmentPortIdentityModel struct {
+	SegmentId types.String `tfsdk:"segment_id"`
+	PortId    types.String `tfsdk:"port_id"`
+	Context   types.String `tfsdk:"context"`
 }
 
 func (r *SegmentPortResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
@@ -59,6 +90,7 @@ func (r *SegmentPortResource) Configure(_ context.Context, req resource.Configur
 	}
 
 	r.client = p.Client
+	r.retryOnRevisionConflict = p.RetryOnRevisionConflict
 }
 
 // Metadata returns the resource type name.
@@ -66,24 +98,30 @@ func (r *SegmentPortResource) Metadata(_ context.Context, req resource.MetadataR
 	resp.TypeName = req.ProviderTypeName + "_segment_port"
 }
 
-func (r *SegmentPortResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *SegmentPortResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Manage a segment port.",
+		MarkdownDescription: "Manage a segment port.\n\n" +
+			"Only segments directly under `/infra` are supported. Ports on segments in a tier-1 gateway or project context, " +
+			"such as `/infra/tier-1s/<tier1_id>` or `/orgs/default/projects/<project_id>`, can't be managed or imported yet, " +
+			"so the `context` of the resource identity is always null.",
 		Attributes: map[string]schema.Attribute{
 			"segment_id": schema.StringAttribute{
-				Description:         "Identifier for this segment.",
-				MarkdownDescription: "Identifier for this segment.",
+				Description:         "Identifier for this segment. Changing it replaces the port.",
+				MarkdownDescription: "Identifier for this segment. Changing it replaces the port.",
 				Required:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
+					requiresReplace("The port belongs to the segment, so moving it to another segment replaces it. Updating it in place would leave the port on the old segment unmanaged."),
 				},
 			},
 			"port_id": schema.StringAttribute{
-				Description:         "Identifier for this port.",
-				MarkdownDescription: "Identifier for this port.",
+				Description:         "Identifier for this port. Changing it replaces the port.",
+				MarkdownDescription: "Identifier for this port. Changing it replaces the port.",
 				Required:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
+					requiresReplace("The port ID identifies the port in NSX, so changing it replaces the port. Updating it in place would leave the old port unmanaged."),
 				},
 			},
 			"segment_port": schema.SingleNestedAttribute{
@@ -101,16 +139,25 @@ func (r *SegmentPortResource) Schema(_ context.Context, _ resource.SchemaRequest
 									Description:         "IP address of segment port",
 									MarkdownDescription: "IP address of segment port",
 									Required:            true,
+									Validators: []validator.String{
+										ipAddress(),
+									},
 								},
 								"mac_address": schema.StringAttribute{
 									Description:         "MAC address of segment port",
 									MarkdownDescription: "MAC address of segment port",
 									Required:            true,
+									Validators: []validator.String{
+										macAddress(),
+									},
 								},
 								"vlan_id": schema.Int32Attribute{
 									Description:         "VLAN ID associated with this segment port",
 									MarkdownDescription: "VLAN ID associated with this segment port",
 									Required:            true,
+									Validators: []validator.Int32{
+										int32validator.Between(0, 4094),
+									},
 								},
 							},
 						},
@@ -119,6 +166,9 @@ func (r *SegmentPortResource) Schema(_ context.Context, _ resource.SchemaRequest
 						Description:         "Admin state of the segment port. Can only be UP or DOWN values.",
 						MarkdownDescription: "Admin state of the segment port. Can only be UP or DOWN values.",
 						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.OneOf("UP", "DOWN"),
+						},
 					},
 					"attachment": schema.SingleNestedAttribute{
 						Description:         "Attachment object definition",
@@ -129,21 +179,33 @@ func (r *SegmentPortResource) Schema(_ context.Context, _ resource.SchemaRequest
 								Description:         "VIF UUID in NSX. Required if type is PARENT.",
 								MarkdownDescription: "VIF UUID in NSX. Required if type is PARENT.",
 								Optional:            true,
+								Validators: []validator.String{
+									uuid(),
+								},
 							},
 							"context_id": schema.StringAttribute{
 								Description:         "Attachment UUID of the PARENT port. Only required when type is CHILD.",
 								MarkdownDescription: "Attachment UUID of the PARENT port. Only required when type is CHILD.",
 								Optional:            true,
+								Validators: []validator.String{
+									uuid(),
+								},
 							},
 							"traffic_tag": schema.Int32Attribute{
-								Description:         "VLAN ID to tag traffic with. Only required when type is CHILD.",
-								MarkdownDescription: "VLAN ID to tag traffic with. Only required when type is CHILD.",
+								Description:         "VLAN ID to tag traffic with. Required when type is CHILD, and not allowed when type is PARENT, whose traffic is untagged.",
+								MarkdownDescription: "VLAN ID to tag traffic with. Required when type is CHILD, and not allowed when type is PARENT, whose traffic is untagged.",
 								Optional:            true,
+								Validators: []validator.Int32{
+									int32validator.Between(0, 4094),
+								},
 							},
 							"allocate_addresses": schema.StringAttribute{
 								Description:         "Indicate how IP will be allocated for the port. Enum: IP_POOL, MAC_POOL, BOTH, DHCP, DHCPV6, SLAAC, NONE",
 								MarkdownDescription: "Indicate how IP will be allocated for the port. Enum: IP_POOL, MAC_POOL, BOTH, DHCP, DHCPV6, SLAAC, NONE",
 								Optional:            true,
+								Validators: []validator.String{
+									stringvalidator.OneOf("IP_POOL", "MAC_POOL", "BOTH", "DHCP", "DHCPV6", "SLAAC", "NONE"),
+								},
 							},
 							"app_id": schema.StringAttribute{
 								Description:         "Application ID associated with this port. Can be the same as the display name. Only required when type is CHILD.",
@@ -151,9 +213,15 @@ func (r *SegmentPortResource) Schema(_ context.Context, _ resource.SchemaRequest
 								Optional:            true,
 							},
 							"type": schema.StringAttribute{
-								Description:         "Type of attachment. Case sensitive. Can be either PARENT or CHILD.",
-								MarkdownDescription: "Type of attachment. Case sensitive. Can be either PARENT or CHILD.",
+								Description:         "Type of attachment. Case sensitive. Can be either PARENT or CHILD. Changing it replaces the port, except that a PARENT port can't be changed to a CHILD port unless segment_id or port_id change too.",
+								MarkdownDescription: "Type of attachment. Case sensitive. Can be either PARENT or CHILD. Changing it replaces the port, except that a PARENT port can't be changed to a CHILD port unless segment_id or port_id change too.",
 								Required:            true,
+								Validators: []validator.String{
+									stringvalidator.OneOf("PARENT", "CHILD"),
+								},
+								PlanModifiers: []planmodifier.String{
+									attachmentTypeChange(),
+								},
 							},
 						},
 					},
@@ -191,10 +259,60 @@ func (r *SegmentPortResource) Schema(_ context.Context, _ resource.SchemaRequest
 						Description:         "Resource type of segment port. MUST be set to 'SegmentPort'",
 						MarkdownDescription: "Resource type of segment port. Can only be set to 'SegmentPort'",
 						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.OneOf("SegmentPort"),
+						},
+					},
+					"revision": schema.Int64Attribute{
+						Description:         "NSX revision of the segment port. Sent with updates so that changes made outside Terraform are not overwritten.",
+						MarkdownDescription: "NSX revision of the segment port. Sent with updates so that changes made outside Terraform are not overwritten.",
+						Computed:            true,
 					},
 				},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create:            true,
+				CreateDescription: `How long creating the port may take, including reading it back from NSX. A duration such as "30s" or "10m". Defaults to 10m.`,
+				Read:              true,
+				ReadDescription:   `How long reading the port may take during a refresh. A duration such as "30s" or "10m". Defaults to 5m.`,
+				Update:            true,
+				UpdateDescription: `How long updating the port may take, including reading it back from NSX. A duration such as "30s" or "10m". Defaults to 10m.`,
+				Delete:            true,
+				DeleteDescription: `How long deleting or detaching the port may take. A duration such as "30s" or "10m". Defaults to 10m.`,
+			}),
+		},
+	}
+}
+
+// IdentitySchema describes the identity Terraform 1.12 and later use to import
+// a segment port with an import block, instead of an import ID.
+func (r *SegmentPortResource) IdentitySchema(_ context.Context, _ resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identityschema.Schema{
+		Attributes: map[string]identityschema.Attribute{
+			"segment_id": identityschema.StringAttribute{
+				Description:       "The ID of the segment the port is attached to.",
+				RequiredForImport: true,
+			},
+			"port_id": identityschema.StringAttribute{
+				Description:       "The ID of the segment port.",
+				RequiredForImport: true,
+			},
+			"context": identityschema.StringAttribute{
+				Description: "The policy path of the tier-1 gateway or project the segment belongs to, " +
+					"such as /infra/tier-1s/<tier1_id> or /orgs/default/projects/<project_id>. " +
+					"Leave unset, or set to /infra, for segments directly under /infra, which are the only segments this provider manages at the moment.",
+				OptionalForImport: true,
+			},
+		},
+	}
+}
+
+// ConfigValidators checks the attributes which depend on the attachment type.
+func (r *SegmentPortResource) ConfigValidators(_ context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		segmentPortAttachmentValidator{},
 	}
 }
 
@@ -209,6 +327,14 @@ func (r *SegmentPortResource) Create(ctx context.Context, req resource.CreateReq
 		return
 	}
 
+	timeout, diags := plan.Timeouts.Create(ctx, segmentPortCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	segmentId := plan.SegmentId.ValueString()
 	tflog.Debug(ctx, fmt.Sprintf("Segment ID: %s", segmentId))
 	portId := plan.PortId.ValueString()
@@ -222,81 +348,38 @@ func (r *SegmentPortResource) Create(ctx context.Context, req resource.CreateReq
 	}
 
 	// Create new item
-	var spResponse *http.Response
 	var err error
 	// For a child port, we are creating it from scratch, so we call PutSegmentPort
 	// For a parent port, we are updating the existing, so we call PatchSegmentPort
 	if patchRequest.ApiSegmentPort.Attachment.Type == "CHILD" {
-		spResponse, err = r.client.PutSegmentPort(ctx, patchRequest)
+		var putResponse *helpers.ApiSegmentPort
+		putResponse, err = r.client.PutSegmentPort(ctx, patchRequest)
+		tflog.Debug(ctx, "Create Segment Port response", map[string]any{"segment_port": putResponse})
 	} else {
-		spResponse, err = r.client.PatchSegmentPort(ctx, patchRequest)
+		err = r.client.PatchSegmentPort(ctx, patchRequest)
 	}
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to Create Segment Port",
-			err.Error(),
-		)
-		return
-	}
-	if spResponse == nil {
-		resp.Diagnostics.AddError(
-			"Response to Create Segment Port was nil",
-			"Response to Create Segment Port was nil",
-		)
-		return
-	}
-
-	bodyBytes, err := io.ReadAll(spResponse.Body)
-	if err != nil {
-		tflog.Debug(ctx, "Unable to read response body")
-	}
-	tflog.Debug(ctx, "Create Segment Port response body: "+string(bodyBytes))
-
-	if spResponse.StatusCode != 200 {
-		resp.Diagnostics.AddError(
-			"An invalid response was received. Code: "+strconv.Itoa(spResponse.StatusCode),
-			spResponse.Status,
-		)
+		addClientError(&resp.Diagnostics, "Unable to Create Segment Port", err)
 		return
 	}
 
 	// We now need to read the port as the Patch function doesn't give us the port details
-	readResponse, err := r.client.GetSegmentPort(ctx, segmentId, portId)
+	newSegmentPort, err := r.client.GetSegmentPort(ctx, segmentId, portId)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to Read Segment Port",
-			err.Error(),
-		)
-		return
-	}
-	tflog.Debug(ctx, "Read segment port response", map[string]any{"response": readResponse})
-
-	if readResponse.StatusCode != 200 {
-		resp.Diagnostics.AddError(
-			"An invalid response was received. Code: "+strconv.Itoa(readResponse.StatusCode),
-			spResponse.Status,
-		)
-		return
-	}
-
-	var newSegmentPort helpers.ApiSegmentPort
-	if err := json.NewDecoder(readResponse.Body).Decode(&newSegmentPort); err != nil {
-		resp.Diagnostics.AddError(
-			"Invalid format received for segment port",
-			err.Error(),
-		)
+		addClientError(&resp.Diagnostics, "Unable to Read Segment Port", err)
 		return
 	}
 	tflog.Debug(ctx, "Created segment port resource", map[string]any{"segment_port": newSegmentPort})
 
 	// This should contain the computed values as well.
-	tfSegmentPort := helpers.ConvertSegmentPortToTF(newSegmentPort)
+	tfSegmentPort := helpers.ConvertSegmentPortToTF(*newSegmentPort)
 	plan.SegmentPort = &tfSegmentPort
 	tflog.Debug(ctx, "COMPUTED SEGMENT PORT", map[string]any{"segment_port": tfSegmentPort})
 
 	// Set state to fully populated data
 	diags = resp.State.Set(ctx, plan)
 	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(setSegmentPortIdentity(ctx, resp.Identity, plan.SegmentId, plan.PortId)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -314,51 +397,40 @@ func (r *SegmentPortResource) Read(ctx context.Context, req resource.ReadRequest
 		return
 	}
 
-	spResponse, err := r.client.GetSegmentPort(ctx, state.SegmentId.ValueString(), state.PortId.ValueString())
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to Read Segment Port configuration",
-			err.Error(),
-		)
+	timeout, diags := state.Timeouts.Read(ctx, segmentPortReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
+	newSegmentPort, err := r.client.GetSegmentPort(ctx, state.SegmentId.ValueString(), state.PortId.ValueString())
 	// Treat HTTP 404 Not Found status as a signal to remove/recreate resource
-	if spResponse.StatusCode == http.StatusNotFound {
+	if client.IsNotFound(err) {
 		resp.State.RemoveResource(ctx)
 		return
 	}
-
-	if spResponse.StatusCode != http.StatusOK {
-		resp.Diagnostics.AddError(
-			"Unexpected HTTP error code received for segment port",
-			spResponse.Status,
-		)
-		return
-	}
-
-	var newSegmentPort helpers.ApiSegmentPort
-	if err := json.NewDecoder(spResponse.Body).Decode(&newSegmentPort); err != nil {
-		resp.Diagnostics.AddError(
-			"Invalid format received for segment port",
-			err.Error(),
-		)
+	if err != nil {
+		addClientError(&resp.Diagnostics, "Unable to Read Segment Port configuration", err)
 		return
 	}
 	tflog.Debug(ctx, "Read segment port resource", map[string]any{"segment_port": newSegmentPort})
 
 	// Map response body to model
-	convertedSegment := helpers.ConvertSegmentPortToTF(newSegmentPort)
+	convertedSegment := helpers.ConvertSegmentPortToTF(*newSegmentPort)
 	state = SegmentPortResourceModel{
 		SegmentId:   state.SegmentId,
 		PortId:      state.PortId,
 		SegmentPort: &convertedSegment,
+		Timeouts:    state.Timeouts,
 	}
 	tflog.Debug(ctx, "Conversion complete", map[string]any{"segment_port": convertedSegment})
 
 	// Set refreshed state
 	diags = resp.State.Set(ctx, &state)
 	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(setSegmentPortIdentity(ctx, resp.Identity, state.SegmentId, state.PortId)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -374,6 +446,14 @@ func (r *SegmentPortResource) Update(ctx context.Context, req resource.UpdateReq
 		return
 	}
 
+	timeout, diags := plan.Timeouts.Update(ctx, segmentPortUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	tflog.Debug(ctx, fmt.Sprintf("Segment ID to update: %s", plan.SegmentId.ValueString()))
 	segmentId := plan.SegmentId.ValueString()
 	tflog.Debug(ctx, fmt.Sprintf("Port ID to update: %s", plan.PortId.ValueString()))
@@ -381,6 +461,17 @@ func (r *SegmentPortResource) Update(ctx context.Context, req resource.UpdateReq
 	tflog.Debug(ctx, fmt.Sprintf("Segment Port details: %+v", &plan.SegmentPort))
 	segmentPort := helpers.ConvertTFToSegmentPort(*plan.SegmentPort)
 
+	// The revision is computed, so it is unknown in the plan. Send the revision
+	// we last read so NSX rejects the update if the port has changed since.
+	var state SegmentPortResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.SegmentPort != nil {
+		segmentPort.Revision = helpers.ConvertTFToSegmentPort(*state.SegmentPort).Revision
+	}
+
 	patchRequest := helpers.PatchSegmentPortRequest{
 		SegmentId:      segmentId,
 		PortId:         portId,
@@ -388,58 +479,47 @@ func (r *SegmentPortResource) Update(ctx context.Context, req resource.UpdateReq
 	}
 	tflog.Debug(ctx, fmt.Sprintf("Updating segment port with request %+v", req))
 
-	// Create new item
-	spResponse, err := r.client.PatchSegmentPort(ctx, patchRequest)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to Create Segment Port",
-			err.Error(),
-		)
-		return
+	// Update existing item
+	err := r.client.PatchSegmentPort(ctx, patchRequest)
+	if client.IsPreconditionFailed(err) && r.retryOnRevisionConflict {
+		tflog.Info(ctx, "Segment port revision changed outside Terraform. Re-reading the port and retrying the update")
+		current, getErr := r.client.GetSegmentPort(ctx, segmentId, portId)
+		if getErr != nil {
+			addClientError(&resp.Diagnostics, "Unable to Read Segment Port", getErr)
+			return
+		}
+		patchRequest.ApiSegmentPort.Revision = current.Revision
+		err = r.client.PatchSegmentPort(ctx, patchRequest)
 	}
-	tflog.Debug(ctx, fmt.Sprintf("PatchSegmentPort response: %+v", spResponse))
-
-	if spResponse.StatusCode != 200 {
+	if client.IsPreconditionFailed(err) {
 		resp.Diagnostics.AddError(
-			fmt.Sprintf("An invalid response was received. Code: %d", spResponse.StatusCode),
-			spResponse.Status,
+			"Segment Port Changed Outside Terraform",
+			fmt.Sprintf("Segment port %s on segment %s was modified outside Terraform after it was last read, so the update was rejected by NSX. "+
+				"Run terraform apply again to refresh the port and review the changes, "+
+				"or set retry_on_revision_conflict = true in the provider configuration to retry automatically.\n\n%s", portId, segmentId, err),
 		)
 		return
 	}
-
-	// We now need to read the port as the Patch function doesn't give us the port details
-	readResponse, err := r.client.GetSegmentPort(ctx, segmentId, portId)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to Read Segment Port",
-			err.Error(),
-		)
+		addClientError(&resp.Diagnostics, "Unable to Update Segment Port", err)
 		return
 	}
-	tflog.Debug(ctx, "Read segment port response", map[string]any{"response": readResponse})
 
-	if readResponse.StatusCode != 200 {
-		resp.Diagnostics.AddError(
-			"An invalid response was received. Code: "+strconv.Itoa(readResponse.StatusCode),
-			spResponse.Status,
-		)
+	// We now need to read the port as the Patch function doesn't give us the port details
+	updatedSegmentPort, err := r.client.GetSegmentPort(ctx, segmentId, portId)
+	if err != nil {
+		addClientError(&resp.Diagnostics, "Unable to Read Segment Port", err)
 		return
 	}
+	tflog.Debug(ctx, "Read segment port response", map[string]any{"segment_port": updatedSegmentPort})
 
-	var updatedSegmentPort helpers.ApiSegmentPort
-	if err := json.NewDecoder(readResponse.Body).Decode(&updatedSegmentPort); err != nil {
-		resp.Diagnostics.AddError(
-			"Invalid format received for segment ports",
-			err.Error(),
-		)
-		return
-	}
-	convertedSegment := helpers.ConvertSegmentPortToTF(updatedSegmentPort)
+	convertedSegment := helpers.ConvertSegmentPortToTF(*updatedSegmentPort)
 	tflog.Debug(ctx, fmt.Sprintf("Converted segment port to TF: %+v", convertedSegment))
 	plan.SegmentPort = &convertedSegment
 
 	// Set state to fully populated data
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	resp.Diagnostics.Append(setSegmentPortIdentity(ctx, resp.Identity, plan.SegmentId, plan.PortId)...)
 	if resp.Diagnostics.HasError() {
 		tflog.Debug(ctx, fmt.Sprintf("Error encountered setting state: %s", resp.Diagnostics.Errors()))
 		return
@@ -457,32 +537,96 @@ func (r *SegmentPortResource) Delete(ctx context.Context, req resource.DeleteReq
 		return
 	}
 
+	timeout, diags := state.Timeouts.Delete(ctx, segmentPortDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	// delete item
-	_, err := r.client.DeleteSegmentPort(ctx, state.SegmentId.ValueString(), state.PortId.ValueString())
+	err := r.client.DeleteSegmentPort(ctx, state.SegmentId.ValueString(), state.PortId.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to Delete Item",
-			err.Error(),
-		)
+		addClientError(&resp.Diagnostics, "Unable to Delete Item", err)
 		return
 	}
 	tflog.Debug(ctx, "Deleted segment port resource", map[string]any{"success": true})
 }
 
+// ImportState accepts either "<segment_id>/<port_id>" or the port's policy
+// path, "/infra/segments/<segment_id>/ports/<port_id>", as the import ID. With
+// Terraform 1.12 and later the port can instead be imported by its identity.
+// The segment_port object is filled in by the Read which follows the import.
 func (r *SegmentPortResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	// Retrieve import ID and save to id attribute
-	// If our ID was a string then we could do this
-	resource.ImportStatePassthroughID(ctx, path.Root("port_id"), req, resp)
-
-	//id, err := strconv.ParseInt(req.ID, 10, 64)
-	//
-	//if err != nil {
-	//	resp.Diagnostics.AddError(
-	//		"Error importing item",
-	//		"Could not import item, unexpected error (ID should be an integer): "+err.Error(),
-	//	)
-	//	return
-	//}
-
-	//resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), id)...)
+	var segmentId, portId string
+	if req.ID != "" {
+		var err error
+		segmentId, portId, err = parseSegmentPortImportID(req.ID)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid Segment Port Import ID", err.Error())
+			return
+		}
+	} else {
+		// Terraform before 1.12 sends no identity, so with an empty ID there
+		// is nothing to import.
+		if req.Identity == nil {
+			resp.Diagnostics.AddError(
+				"Invalid Segment Port Import ID",
+				"The import ID is empty. Import the port with an ID of \"<segment_id>/<port_id>\" or the port's policy path, "+
+					"or with an import block which sets identity.",
+			)
+			return
+		}
+		var identity SegmentPortIdentityModel
+		resp.Diagnostics.Append(req.Identity.Get(ctx, &identity)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if !identity.Context.IsNull() && identity.Context.ValueString() != "/infra" {
+			resp.Diagnostics.AddError(
+				"Unsupported Segment Port Context",
+				fmt.Sprintf("The import identity sets context to %q, but only segments directly under /infra can be managed by this provider, "+
+					"so segment ports in a tier-1 gateway or project context can't be imported. "+
+					"Leave context unset for segments under /infra.", identity.Context.ValueString()),
+			)
+			return
+		}
+		segmentId, portId = identity.SegmentId.ValueString(), identity.PortId.ValueString()
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("segment_id"), segmentId)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("port_id"), portId)...)
+	resp.Diagnostics.Append(setSegmentPortIdentity(ctx, resp.Identity, types.StringValue(segmentId), types.StringValue(portId))...)
+}
+
+// setSegmentPortIdentity records the identity of a port. identity is nil when
+// Terraform is too old to support resource identity. The context is null, as
+// only segments under /infra are managed.
+func setSegmentPortIdentity(ctx context.Context, identity *tfsdk.ResourceIdentity, segmentId, portId types.String) diag.Diagnostics {
+	if identity == nil {
+		return nil
+	}
+	return identity.Set(ctx, SegmentPortIdentityModel{
+		SegmentId: segmentId,
+		PortId:    portId,
+		Context:   types.StringNull(),
+	})
+}
+
+// parseSegmentPortImportID splits an import ID into the segment and port IDs.
+func parseSegmentPortImportID(id string) (string, string, error) {
+	parts := strings.Split(id, "/")
+	switch {
+	case len(parts) == 2:
+		// segment_id/port_id
+	case len(parts) == 6 && parts[0] == "" && parts[1] == "infra" && parts[2] == "segments" && parts[4] == "ports":
+		parts = []string{parts[3], parts[5]}
+	default:
+		parts = nil
+	}
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf(`expected an import ID of the form "<segment_id>/<port_id>" or "/infra/segments/<segment_id>/ports/<port_id>", got: %q`, id)
+	}
+	return parts[0], parts[1], nil
 }
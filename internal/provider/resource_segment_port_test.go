@@ -3,208 +3,482 @@
 
 package provider
 
-//
-//import (
-//	"testing"
-//
-//	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
-//	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
-//	"github.com/hashicorp/terraform-plugin-testing/statecheck"
-//	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
-//)
-//
-//func TestAccSegmentPortParentResource(t *testing.T) {
-//	resource.Test(t, resource.TestCase{
-//		PreCheck:                 func() { testAccPreCheck(t) },
-//		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
-//		Steps: []resource.TestStep{
-//			// Create and Read testing
-//			{
-//				Config: testAccSegmentPortResourceParentConfig,
-//				ConfigStateChecks: []statecheck.StateCheck{
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_id"),
-//						knownvalue.StringExact("4d4c0f0a-6c5 0-420b-90f1-68fb7585cda4"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("port_id"),
-//						knownvalue.StringExact("a274ac51-88f5-491f-a46f-840d409ce82f"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.admin_state"),
-//						knownvalue.StringExact("UP"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.attachment.id"),
-//						knownvalue.StringExact("9765bf41-9725-4714-977e-7f7395920de2"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.attachment.traffic_tag"),
-//						knownvalue.StringExact("1000"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.attachment.type"),
-//						knownvalue.StringExact("PARENT"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.description"),
-//						knownvalue.StringExact("GCVE-PA-VM-ESX-2 Parent Port"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.display_name"),
-//						knownvalue.StringExact("GCVE-PA-VM-ESX-2.vmx@060af2c2-e9ff-4686-866c-c0daab1748d6"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.id"),
-//						knownvalue.StringExact("060af2c2-e9ff-4686-866c-c0daab1748d6"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.resource_type"),
-//						knownvalue.StringExact("SegmentPort"),
-//					),
-//				},
-//			},
-//		},
-//	})
-//}
-//
-//func TestAccSegmentPortChildResource(t *testing.T) {
-//	resource.Test(t, resource.TestCase{
-//		PreCheck:                 func() { testAccPreCheck(t) },
-//		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithEcho,
-//		Steps: []resource.TestStep{
-//			// Create and Read testing
-//			{
-//				Config: testAccSegmentPortResourceChildConfig,
-//				ConfigStateChecks: []statecheck.StateCheck{
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_id"),
-//						knownvalue.StringExact("2bfe8abf-4161-4788-9cbe-c444e9bf7454"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("port_id"),
-//						knownvalue.StringExact("a274ac51-88f5-491f-a46f-840d409ce82f"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.address_bindings.ip_address"),
-//						knownvalue.StringExact("169.254.254.169"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.address_bindings.mac_address"),
-//						knownvalue.StringExact("00:50:56:ad:5e:64"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.address_bindings.vlan_id"),
-//						knownvalue.StringExact("1001"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.admin_state"),
-//						knownvalue.StringExact("UP"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.attachment.context_id"),
-//						knownvalue.StringExact("9765bf41-9725-4714-977e-7f7395920de2"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.attachment.traffic_tag"),
-//						knownvalue.StringExact("1001"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.attachment.app_id"),
-//						knownvalue.StringExact("Segment1001"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.attachment.type"),
-//						knownvalue.StringExact("CHILD"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.description"),
-//						knownvalue.StringExact("GCVE-PA-VM-ESX-2 Child Port 1001"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.display_name"),
-//						knownvalue.StringExact("GCVE-PA-VM-ESX-2.vmx@a274ac51-88f5-491f-a46f-840d409ce82f"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.id"),
-//						knownvalue.StringExact("a274ac51-88f5-491f-a46f-840d409ce82f"),
-//					),
-//					statecheck.ExpectKnownValue(
-//						"nsx_intervlan_routing_segment_port.parent_example",
-//						tfjsonpath.New("segment_port.resource_type"),
-//						knownvalue.StringExact("SegmentPort"),
-//					),
-//				},
-//			},
-//		},
-//	})
-//}
-//
-//const testAccSegmentPortResourceParentConfig = `
-//resource "nsx_intervlan_routing_segment_port" "parent_example" {
-//  segment_id = "4d4c0f0a-6c5 0-420b-90f1-68fb7585cda4"
-//  port_id    = "a274ac51-88f5-491f-a46f-840d409ce82f"
-//  segment_port = {
-//    admin_state = "UP"
-//    attachment = {
-//      id          = "9765bf41-9725-4714-977e-7f7395920de2"
-//      traffic_tag = "1000"
-//      type        = "PARENT"
-//    }
-//    description   = "GCVE-PA-VM-ESX-2 Parent Port"
-//    display_name  = "GCVE-PA-VM-ESX-2.vmx@060af2c2-e9ff-4686-866c-c0daab1748d6"
-//    id            = "060af2c2-e9ff-4686-866c-c0daab1748d6"
-//    resource_type = "SegmentPort"
-//  }
-//}
-//`
-//
-//const testAccSegmentPortResourceChildConfig = `
-//resource "nsx_intervlan_routing_segment_port" "child_example" {
-//  segment_id = "2bfe8abf-4161-4788-9cbe-c444e9bf7454"
-//  port_id    = "a274ac51-88f5-491f-a46f-840d409ce82f"
-//  segment_port = {
-//    address_bindings = [
-//      {
-//        ip_address  = "169.254.254.169"
-//        mac_address = "00:50:56:ad:5e:64"
-//        vlan_id     = "1001"
-//      },
-//    ]
-//    admin_state = "UP"
-//    attachment = {
-//      context_id  = "9765bf41-9725-4714-977e-7f7395920de2"
-//      traffic_tag = "1001"
-//      app_id      = "Segment1001"
-//      type        = "CHILD"
-//    }
-//    description   = "GCVE-PA-VM-ESX-2 Child Port 1001"
-//    display_name  = "GCVE-PA-VM-ESX-2.vmx@a274ac51-88f5-491f-a46f-840d409ce82f"
-//    id            = "a274ac51-88f5-491f-a46f-840d409ce82f"
-//    resource_type = "SegmentPort"
-//  }
-//}
-//`
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"terraform-provider-nsx-intervlan-routing/helpers"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+const (
+	testAccParentSegmentId = "4d4c0f0a-6c50-420b-90f1-68fb7585cda4"
+	testAccParentPortId    = "060af2c2-e9ff-4686-866c-c0daab1748d6"
+	testAccChildSegmentId  = "2bfe8abf-4161-4788-9cbe-c444e9bf7454"
+	testAccChildPortId     = "a274ac51-88f5-491f-a46f-840d409ce82f"
+
+	testAccChildReplacementPortId = "5f0f6f0e-2f4b-4c59-9d0e-3f7c4a1b2c3d"
+)
+
+func TestAccSegmentPortParentResource(t *testing.T) {
+	server := testAccNsxFake(t)
+	// Parent ports belong to a VM, so they exist before Terraform manages them.
+	server.SetSegmentPort(testAccParentSegmentId, helpers.ApiSegmentPort{
+		Id:          testAccParentPortId,
+		DisplayName: "GCVE-PA-VM-ESX-2.vmx@" + testAccParentPortId,
+		AdminState:  "UP",
+		Attachment:  helpers.ApiPortAttachment{Id: "9765bf41-9725-4714-977e-7f7395920de2", Type: "STATIC"},
+	})
+
+	resourceName := "nsx-intervlan-routing_segment_port.parent_example"
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(_ *terraform.State) error {
+			port, ok := server.SegmentPort(testAccParentSegmentId, testAccParentPortId)
+			if !ok {
+				return fmt.Errorf("parent port was deleted instead of being detached")
+			}
+			if port.Attachment.Type != "STATIC" {
+				return fmt.Errorf("expected parent port to be reverted to STATIC, got %s", port.Attachment.Type)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccProviderConfig(server) + testAccSegmentPortResourceParentConfig("GCVE-PA-VM-ESX-2 Parent Port"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_id"),
+						knownvalue.StringExact(testAccParentSegmentId),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("port_id"),
+						knownvalue.StringExact(testAccParentPortId),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("admin_state"),
+						knownvalue.StringExact("UP"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("attachment").AtMapKey("id"),
+						knownvalue.StringExact("9765bf41-9725-4714-977e-7f7395920de2"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("attachment").AtMapKey("type"),
+						knownvalue.StringExact("PARENT"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("description"),
+						knownvalue.StringExact("GCVE-PA-VM-ESX-2 Parent Port"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("path"),
+						knownvalue.StringExact("/infra/segments/"+testAccParentSegmentId+"/ports/"+testAccParentPortId),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("revision"),
+						knownvalue.Int64Exact(1),
+					),
+				},
+			},
+			// Update and Read testing
+			{
+				Config: testAccProviderConfig(server) + testAccSegmentPortResourceParentConfig("GCVE-PA-VM-ESX-2 Parent Port (updated)"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("description"),
+						knownvalue.StringExact("GCVE-PA-VM-ESX-2 Parent Port (updated)"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("revision"),
+						knownvalue.Int64Exact(2),
+					),
+				},
+			},
+			// ImportState testing, with both forms of import ID
+			{
+				ResourceName:                         resourceName,
+				ImportState:                          true,
+				ImportStateId:                        testAccParentSegmentId + "/" + testAccParentPortId,
+				ImportStateVerify:                    true,
+				ImportStateVerifyIdentifierAttribute: "port_id",
+				ImportStateVerifyIgnore:              []string{"timeouts"},
+			},
+			{
+				ResourceName:                         resourceName,
+				ImportState:                          true,
+				ImportStateId:                        "/infra/segments/" + testAccParentSegmentId + "/ports/" + testAccParentPortId,
+				ImportStateVerify:                    true,
+				ImportStateVerifyIdentifierAttribute: "port_id",
+				ImportStateVerifyIgnore:              []string{"timeouts"},
+			},
+		},
+	})
+}
+
+func TestAccSegmentPortResourceIdentity(t *testing.T) {
+	server := testAccNsxFake(t)
+	server.SetSegmentPort(testAccParentSegmentId, helpers.ApiSegmentPort{
+		Id:          testAccParentPortId,
+		DisplayName: "GCVE-PA-VM-ESX-2.vmx@" + testAccParentPortId,
+		AdminState:  "UP",
+		Attachment:  helpers.ApiPortAttachment{Id: "9765bf41-9725-4714-977e-7f7395920de2", Type: "STATIC"},
+	})
+
+	resourceName := "nsx-intervlan-routing_segment_port.parent_example"
+	config := testAccProviderConfig(server) + testAccSegmentPortResourceParentConfig("GCVE-PA-VM-ESX-2 Parent Port")
+	resource.Test(t, resource.TestCase{
+		// Resource identity was added in Terraform 1.12.
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_12_0),
+		},
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectIdentity(resourceName, map[string]knownvalue.Check{
+						"segment_id": knownvalue.StringExact(testAccParentSegmentId),
+						"port_id":    knownvalue.StringExact(testAccParentPortId),
+						"context":    knownvalue.Null(),
+					}),
+				},
+			},
+			{
+				ResourceName:    resourceName,
+				Config:          config,
+				ImportState:     true,
+				ImportStateKind: resource.ImportBlockWithResourceIdentity,
+			},
+		},
+	})
+}
+
+func TestAccSegmentPortChildResource(t *testing.T) {
+	server := testAccNsxFake(t)
+	server.AddSegment(testAccChildSegmentId)
+
+	resourceName := "nsx-intervlan-routing_segment_port.child_example"
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(_ *terraform.State) error {
+			for _, portId := range []string{testAccChildPortId, testAccChildReplacementPortId} {
+				if _, ok := server.SegmentPort(testAccChildSegmentId, portId); ok {
+					return fmt.Errorf("child port %s still exists", portId)
+				}
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccProviderConfig(server) + testAccSegmentPortResourceChildConfig,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_id"),
+						knownvalue.StringExact(testAccChildSegmentId),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("port_id"),
+						knownvalue.StringExact(testAccChildPortId),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("address_bindings").AtSliceIndex(0).AtMapKey("ip_address"),
+						knownvalue.StringExact("169.254.254.169"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("address_bindings").AtSliceIndex(0).AtMapKey("mac_address"),
+						knownvalue.StringExact("00:50:56:ad:5e:64"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("address_bindings").AtSliceIndex(0).AtMapKey("vlan_id"),
+						knownvalue.Int32Exact(1001),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("attachment").AtMapKey("context_id"),
+						knownvalue.StringExact("9765bf41-9725-4714-977e-7f7395920de2"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("attachment").AtMapKey("traffic_tag"),
+						knownvalue.Int32Exact(1001),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("attachment").AtMapKey("app_id"),
+						knownvalue.StringExact("Segment1001"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("attachment").AtMapKey("type"),
+						knownvalue.StringExact("CHILD"),
+					),
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("segment_port").AtMapKey("revision"),
+						knownvalue.Int64Exact(0),
+					),
+				},
+			},
+			// Moving the port to another ID replaces it rather than patching
+			// a different port in place.
+			{
+				Config: testAccProviderConfig(server) + strings.ReplaceAll(testAccSegmentPortResourceChildConfig, testAccChildPortId, testAccChildReplacementPortId),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(resourceName, plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						resourceName,
+						tfjsonpath.New("port_id"),
+						knownvalue.StringExact(testAccChildReplacementPortId),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccSegmentPortResourceValidation(t *testing.T) {
+	server := testAccNsxFake(t)
+	server.AddSegment(testAccChildSegmentId)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccProviderConfig(server) + strings.Replace(testAccSegmentPortResourceChildConfig, `admin_state = "UP"`, `admin_state = "up"`, 1),
+				ExpectError: regexp.MustCompile(`value must be one of: \["UP" "DOWN"\]`),
+				PlanOnly:    true,
+			},
+			{
+				Config:      testAccProviderConfig(server) + strings.Replace(testAccSegmentPortResourceChildConfig, "traffic_tag = 1001", "traffic_tag = 4095", 1),
+				ExpectError: regexp.MustCompile(`must be between 0 and 4094`),
+				PlanOnly:    true,
+			},
+			{
+				Config:      testAccProviderConfig(server) + strings.Replace(testAccSegmentPortResourceChildConfig, `"00:50:56:ad:5e:64"`, `"00:50:56:ad:5e"`, 1),
+				ExpectError: regexp.MustCompile(`must be a MAC address`),
+				PlanOnly:    true,
+			},
+			{
+				Config:      testAccProviderConfig(server) + strings.Replace(testAccSegmentPortResourceChildConfig, `app_id      = "Segment1001"`, "", 1),
+				ExpectError: regexp.MustCompile(`app_id is required when segment_port.attachment.type is CHILD`),
+				PlanOnly:    true,
+			},
+		},
+	})
+}
+
+func testAccSegmentPortResourceParentConfig(description string) string {
+	return fmt.Sprintf(`
+resource "nsx-intervlan-routing_segment_port" "parent_example" {
+  segment_id = %[1]q
+  port_id    = %[2]q
+  segment_port = {
+    admin_state = "UP"
+    attachment = {
+      id   = "9765bf41-9725-4714-977e-7f7395920de2"
+      type = "PARENT"
+    }
+    description   = %[3]q
+    display_name  = "GCVE-PA-VM-ESX-2.vmx@%[2]s"
+    id            = %[2]q
+    resource_type = "SegmentPort"
+  }
+  timeouts {
+    create = "5m"
+    update = "5m"
+  }
+}
+`, testAccParentSegmentId, testAccParentPortId, description)
+}
+
+const testAccSegmentPortResourceChildConfig = `
+resource "nsx-intervlan-routing_segment_port" "child_example" {
+  segment_id = "2bfe8abf-4161-4788-9cbe-c444e9bf7454"
+  port_id    = "a274ac51-88f5-491f-a46f-840d409ce82f"
+  segment_port = {
+    address_bindings = [
+      {
+        ip_address  = "169.254.254.169"
+        mac_address = "00:50:56:ad:5e:64"
+        vlan_id     = 1001
+      },
+    ]
+    admin_state = "UP"
+    attachment = {
+      context_id  = "9765bf41-9725-4714-977e-7f7395920de2"
+      traffic_tag = 1001
+      app_id      = "Segment1001"
+      type        = "CHILD"
+    }
+    description   = "GCVE-PA-VM-ESX-2 Child Port 1001"
+    display_name  = "GCVE-PA-VM-ESX-2.vmx@a274ac51-88f5-491f-a46f-840d409ce82f"
+    id            = "a274ac51-88f5-491f-a46f-840d409ce82f"
+    resource_type = "SegmentPort"
+  }
+}
+`
+
+func TestParseSegmentPortImportID(t *testing.T) {
+	tests := []struct {
+		id                string
+		segmentId, portId string
+		wantErr           bool
+	}{
+		{id: "segment-1/port-1", segmentId: "segment-1", portId: "port-1"},
+		{id: "/infra/segments/segment-1/ports/port-1", segmentId: "segment-1", portId: "port-1"},
+		{id: "port-1", wantErr: true},
+		{id: "/port-1", wantErr: true},
+		{id: "segment-1/", wantErr: true},
+		{id: "/infra/segments/segment-1/ports/", wantErr: true},
+		{id: "/infra/tier-1s/t1/segments/segment-1/ports/port-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		segmentId, portId, err := parseSegmentPortImportID(tt.id)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSegmentPortImportID(%q) error = %v, want error %v", tt.id, err, tt.wantErr)
+			continue
+		}
+		if segmentId != tt.segmentId || portId != tt.portId {
+			t.Errorf("parseSegmentPortImportID(%q) = %q, %q, want %q, %q", tt.id, segmentId, portId, tt.segmentId, tt.portId)
+		}
+	}
+}
+
+func TestSegmentPortImportStateIdentity(t *testing.T) {
+	ctx := context.Background()
+	r := &SegmentPortResource{}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	var identitySchemaResp fwresource.IdentitySchemaResponse
+	r.IdentitySchema(ctx, fwresource.IdentitySchemaRequest{}, &identitySchemaResp)
+
+	newIdentity := func() *tfsdk.ResourceIdentity {
+		return &tfsdk.ResourceIdentity{
+			Schema: identitySchemaResp.IdentitySchema,
+			Raw:    tftypes.NewValue(identitySchemaResp.IdentitySchema.Type().TerraformType(ctx), nil),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		context types.String
+		wantErr bool
+	}{
+		{name: "no context", context: types.StringNull()},
+		{name: "infra context", context: types.StringValue("/infra")},
+		{name: "tier-1 context", context: types.StringValue("/infra/tier-1s/t1"), wantErr: true},
+		{name: "project context", context: types.StringValue("/orgs/default/projects/dev"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := fwresource.ImportStateRequest{Identity: newIdentity()}
+			if diags := req.Identity.Set(ctx, SegmentPortIdentityModel{
+				SegmentId: types.StringValue("segment-1"),
+				PortId:    types.StringValue("port-1"),
+				Context:   tt.context,
+			}); diags.HasError() {
+				t.Fatalf("failed to build the import identity: %v", diags)
+			}
+			resp := fwresource.ImportStateResponse{
+				State: tfsdk.State{
+					Schema: schemaResp.Schema,
+					Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+				},
+				Identity: newIdentity(),
+			}
+
+			r.ImportState(ctx, req, &resp)
+			if resp.Diagnostics.HasError() != tt.wantErr {
+				t.Fatalf("ImportState diagnostics = %v, want error %v", resp.Diagnostics, tt.wantErr)
+			}
+			if tt.wantErr {
+				// context is an identity attribute, so the error can't point
+				// at a path in the resource schema.
+				for _, d := range resp.Diagnostics.Errors() {
+					if _, ok := d.(diag.DiagnosticWithPath); ok {
+						t.Errorf("expected the error not to have an attribute path, got %v", d)
+					}
+				}
+				return
+			}
+
+			var state SegmentPortResourceModel
+			resp.Diagnostics.Append(resp.State.Get(ctx, &state)...)
+			var identity SegmentPortIdentityModel
+			resp.Diagnostics.Append(resp.Identity.Get(ctx, &identity)...)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("failed to read the imported state: %v", resp.Diagnostics)
+			}
+			if state.SegmentId.ValueString() != "segment-1" || state.PortId.ValueString() != "port-1" {
+				t.Errorf("imported segment_id, port_id = %s, %s, want segment-1, port-1", state.SegmentId, state.PortId)
+			}
+			if !identity.Context.IsNull() {
+				t.Errorf("expected the identity context of a segment under /infra to be null, got %s", identity.Context)
+			}
+		})
+	}
+}
+
+func TestSegmentPortImportStateWithoutIDOrIdentity(t *testing.T) {
+	ctx := context.Background()
+	r := &SegmentPortResource{}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	resp := fwresource.ImportStateResponse{
+		State: tfsdk.State{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+		},
+	}
+	r.ImportState(ctx, fwresource.ImportStateRequest{}, &resp)
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error when importing without an ID or identity")
+	}
+	if got := resp.Diagnostics.Errors()[0].Summary(); got != "Invalid Segment Port Import ID" {
+		t.Errorf("error summary = %q, want %q", got, "Invalid Segment Port Import ID")
+	}
+}
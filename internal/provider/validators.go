@@ -0,0 +1,139 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/netip"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	// macAddressRegexp matches a MAC address in the colon separated form NSX
+	// returns, e.g. 00:50:56:ad:5e:64.
+	macAddressRegexp = regexp.MustCompile(`^[0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}$`)
+	// uuidRegexp matches a UUID in its canonical hyphenated form.
+	uuidRegexp = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}$`)
+)
+
+// macAddress validates that a string is a colon separated MAC address.
+func macAddress() validator.String {
+	return stringvalidator.RegexMatches(macAddressRegexp, "must be a MAC address such as 00:50:56:ad:5e:64")
+}
+
+// uuid validates that a string is a UUID.
+func uuid() validator.String {
+	return stringvalidator.RegexMatches(uuidRegexp, "must be a UUID such as 9765bf41-9725-4714-977e-7f7395920de2")
+}
+
+// ipAddress validates that a string is an IPv4 or IPv6 address.
+func ipAddress() validator.String {
+	return ipAddressValidator{}
+}
+
+type ipAddressValidator struct{}
+
+func (v ipAddressValidator) Description(_ context.Context) string {
+	return "value must be an IPv4 or IPv6 address"
+}
+
+func (v ipAddressValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v ipAddressValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := req.ConfigValue.ValueString()
+	if addr, err := netip.ParseAddr(value); err != nil || addr.Zone() != "" {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid IP Address",
+			"Attribute "+req.Path.String()+" "+v.Description(ctx)+", got: "+value,
+		)
+	}
+}
+
+// segmentPortAttachmentValidator checks the attributes a segment port needs
+// for its attachment type. CHILD ports are created by Terraform and need the
+// parent's context, a traffic tag, an app ID and address bindings. PARENT
+// ports already exist for a VM and are identified by their VIF ID; their
+// traffic is untagged.
+type segmentPortAttachmentValidator struct{}
+
+var _ resource.ConfigValidator = segmentPortAttachmentValidator{}
+
+func (v segmentPortAttachmentValidator) Description(_ context.Context) string {
+	return "CHILD ports require context_id, traffic_tag, app_id and address_bindings; PARENT ports require id and can't set traffic_tag"
+}
+
+func (v segmentPortAttachmentValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v segmentPortAttachmentValidator) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	segmentPort := path.Root("segment_port")
+	attachment := segmentPort.AtName("attachment")
+
+	var attachmentType types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, attachment.AtName("type"), &attachmentType)...)
+	if resp.Diagnostics.HasError() || attachmentType.IsNull() || attachmentType.IsUnknown() {
+		return
+	}
+
+	// isSet reads the attribute at p and reports whether it is set. Unknown
+	// values count as set, since they may be once they are known.
+	isSet := func(p path.Path) bool {
+		var value attr.Value
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, p, &value)...)
+		return value != nil && !value.IsNull()
+	}
+
+	switch attachmentType.ValueString() {
+	case "CHILD":
+		for _, name := range []string{"context_id", "traffic_tag", "app_id"} {
+			if !isSet(attachment.AtName(name)) {
+				resp.Diagnostics.AddAttributeError(
+					attachment.AtName(name),
+					"Missing Attribute for CHILD Port",
+					name+" is required when segment_port.attachment.type is CHILD.",
+				)
+			}
+		}
+
+		var bindings types.List
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, segmentPort.AtName("address_bindings"), &bindings)...)
+		if bindings.IsNull() || (!bindings.IsUnknown() && len(bindings.Elements()) == 0) {
+			resp.Diagnostics.AddAttributeError(
+				segmentPort.AtName("address_bindings"),
+				"Missing Attribute for CHILD Port",
+				"At least one address binding is required when segment_port.attachment.type is CHILD.",
+			)
+		}
+	case "PARENT":
+		if !isSet(attachment.AtName("id")) {
+			resp.Diagnostics.AddAttributeError(
+				attachment.AtName("id"),
+				"Missing Attribute for PARENT Port",
+				"id is required when segment_port.attachment.type is PARENT. Set it to the VIF UUID of the VM's port.",
+			)
+		}
+		if isSet(attachment.AtName("traffic_tag")) {
+			resp.Diagnostics.AddAttributeError(
+				attachment.AtName("traffic_tag"),
+				"Invalid Attribute for PARENT Port",
+				"traffic_tag can only be set when segment_port.attachment.type is CHILD. Traffic on the PARENT port is untagged.",
+			)
+		}
+	}
+}
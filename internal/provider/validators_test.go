@@ -0,0 +1,189 @@
+// Copyright (c) Technofish Consulting Pty Ltd.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"terraform-provider-nsx-intervlan-routing/helpers"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestStringValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator validator.String
+		value     string
+		wantError bool
+	}{
+		{"ipv4", ipAddress(), "169.254.254.169", false},
+		{"ipv6", ipAddress(), "fd00::1", false},
+		{"ip with prefix", ipAddress(), "10.0.0.1/24", true},
+		{"ip typo", ipAddress(), "169.254.254", true},
+		{"mac", macAddress(), "00:50:56:ad:5e:64", false},
+		{"mac with dashes", macAddress(), "00-50-56-ad-5e-64", true},
+		{"mac too short", macAddress(), "00:50:56:ad:5e", true},
+		{"uuid", uuid(), "9765bf41-9725-4714-977e-7f7395920de2", false},
+		{"uuid without hyphens", uuid(), "9765bf4197254714977e7f7395920de2", true},
+		{"display name instead of uuid", uuid(), "GCVE-PA-VM-ESX-2", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validator.StringRequest{Path: path.Root("test"), ConfigValue: types.StringValue(tt.value)}
+			var resp validator.StringResponse
+			tt.validator.ValidateString(context.Background(), req, &resp)
+			if resp.Diagnostics.HasError() != tt.wantError {
+				t.Errorf("ValidateString(%q) errors = %v, want error %v", tt.value, resp.Diagnostics, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestStringValidatorsIgnoreUnknown(t *testing.T) {
+	for _, v := range []validator.String{ipAddress(), macAddress(), uuid()} {
+		req := validator.StringRequest{Path: path.Root("test"), ConfigValue: types.StringUnknown()}
+		var resp validator.StringResponse
+		v.ValidateString(context.Background(), req, &resp)
+		if resp.Diagnostics.HasError() {
+			t.Errorf("expected unknown values to be accepted, got %v", resp.Diagnostics)
+		}
+	}
+}
+
+// testSegmentPortConfig builds a resource config from model using the
+// segment_port resource schema.
+func testSegmentPortConfig(t *testing.T, model SegmentPortResourceModel) tfsdk.Config {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp resource.SchemaResponse
+	NewSegmentPortResource().Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	plan := tfsdk.Plan{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}
+	if diags := plan.Set(ctx, model); diags.HasError() {
+		t.Fatalf("unable to build config: %v", diags)
+	}
+	return tfsdk.Config{Schema: plan.Schema, Raw: plan.Raw}
+}
+
+func testSegmentPortModel(attachment helpers.PortAttachment, bindings []helpers.PortAddressBinding) SegmentPortResourceModel {
+	return SegmentPortResourceModel{
+		SegmentId: types.StringValue("segment-1"),
+		PortId:    types.StringValue("port-1"),
+		SegmentPort: &helpers.SegmentPort{
+			AddressBindings: bindings,
+			AdminState:      types.StringValue("UP"),
+			Attachment:      attachment,
+			DisplayName:     types.StringValue("port-1"),
+			Id:              types.StringValue("port-1"),
+			ResourceType:    types.StringValue("SegmentPort"),
+		},
+		Timeouts: timeouts.Value{Object: types.ObjectNull(map[string]attr.Type{
+			"create": types.StringType, "read": types.StringType, "update": types.StringType, "delete": types.StringType,
+		})},
+	}
+}
+
+func TestSegmentPortAttachmentValidator(t *testing.T) {
+	binding := []helpers.PortAddressBinding{{
+		IpAddress:  types.StringValue("169.254.254.169"),
+		MacAddress: types.StringValue("00:50:56:ad:5e:64"),
+		VlanId:     types.Int32Value(1001),
+	}}
+	child := helpers.PortAttachment{
+		Type:       types.StringValue("CHILD"),
+		ContextId:  types.StringValue("9765bf41-9725-4714-977e-7f7395920de2"),
+		TrafficTag: types.Int32Value(1001),
+		AppId:      types.StringValue("Segment1001"),
+	}
+	parent := helpers.PortAttachment{
+		Type: types.StringValue("PARENT"),
+		Id:   types.StringValue("9765bf41-9725-4714-977e-7f7395920de2"),
+	}
+
+	tests := []struct {
+		name       string
+		model      SegmentPortResourceModel
+		wantErrors []path.Path
+	}{
+		{
+			name:  "valid child",
+			model: testSegmentPortModel(child, binding),
+		},
+		{
+			name:  "valid parent",
+			model: testSegmentPortModel(parent, nil),
+		},
+		{
+			name: "child without context, tag, app or bindings",
+			model: testSegmentPortModel(helpers.PortAttachment{
+				Type:       types.StringValue("CHILD"),
+				ContextId:  types.StringNull(),
+				TrafficTag: types.Int32Null(),
+				AppId:      types.StringNull(),
+			}, nil),
+			wantErrors: []path.Path{
+				path.Root("segment_port").AtName("attachment").AtName("context_id"),
+				path.Root("segment_port").AtName("attachment").AtName("traffic_tag"),
+				path.Root("segment_port").AtName("attachment").AtName("app_id"),
+				path.Root("segment_port").AtName("address_bindings"),
+			},
+		},
+		{
+			name: "parent with traffic tag and no id",
+			model: testSegmentPortModel(helpers.PortAttachment{
+				Type:       types.StringValue("PARENT"),
+				TrafficTag: types.Int32Value(1001),
+			}, nil),
+			wantErrors: []path.Path{
+				path.Root("segment_port").AtName("attachment").AtName("id"),
+				path.Root("segment_port").AtName("attachment").AtName("traffic_tag"),
+			},
+		},
+		{
+			name: "unknown type",
+			model: testSegmentPortModel(helpers.PortAttachment{
+				Type: types.StringUnknown(),
+			}, nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := resource.ValidateConfigRequest{Config: testSegmentPortConfig(t, tt.model)}
+			var resp resource.ValidateConfigResponse
+			segmentPortAttachmentValidator{}.ValidateResource(context.Background(), req, &resp)
+
+			var got []path.Path
+			for _, d := range resp.Diagnostics.Errors() {
+				withPath, ok := d.(diag.DiagnosticWithPath)
+				if !ok {
+					t.Fatalf("expected an attribute error, got %v", d)
+				}
+				got = append(got, withPath.Path())
+			}
+			if len(got) != len(tt.wantErrors) {
+				t.Fatalf("expected errors on %v, got %v", tt.wantErrors, resp.Diagnostics)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.wantErrors[i]) {
+					t.Errorf("expected error %d on %s, got %s", i, tt.wantErrors[i], got[i])
+				}
+			}
+		})
+	}
+}
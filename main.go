@@ -6,10 +6,12 @@ package main
 import (
 	"context"
 	"flag"
+	"io"
 	"log"
 
 	"terraform-provider-nsx-intervlan-routing/internal/provider"
 
+	tfprovider "github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 )
 
@@ -36,7 +38,16 @@ func main() {
 		Debug:   debug,
 	}
 
-	err := providerserver.Serve(context.Background(), provider.New(version), opts)
+	// Keep hold of the provider so its NSX sessions can be logged out once
+	// Terraform stops the plugin.
+	p := provider.New(version)()
+	err := providerserver.Serve(context.Background(), func() tfprovider.Provider { return p }, opts)
+
+	if closer, ok := p.(io.Closer); ok {
+		if cerr := closer.Close(); cerr != nil {
+			log.Printf("[WARN] Unable to log out of NSX: %s", cerr)
+		}
+	}
 
 	if err != nil {
 		log.Fatal(err.Error())